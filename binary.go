@@ -0,0 +1,148 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const (
+	// hexRowSize is the number of bytes shown per hexdump row
+	hexRowSize = 16
+)
+
+var (
+	// HexDiffContext is the number of unchanged hexdump rows shown before and
+	// after each differing row by HexDiff
+	HexDiffContext = 1
+	// HexDiffMaxRegions is the maximum number of differing regions shown by
+	// HexDiff, any further regions are summarized
+	HexDiffMaxRegions = 8
+)
+
+// HexDiff returns a description of the differences between two byte slices:
+// the length mismatch (if any), the offset of the first differing byte and a
+// side-by-side hexdump window around each region of differences, with the
+// differing rows marked by a leading `*`. An empty string is returned when
+// want and got are equal
+//
+// Example:
+//
+//	length mismatch: want 20 bytes, got 19 bytes
+//	first difference at offset 0x00000012 (18)
+//	  offset    want                                                                 got
+//	  00000000  00 01 02 03 04 05 06 07 08 09 0a 0b 0c 0d 0e 0f  |................|  00 01 02 03 04 05 06 07 08 09 0a 0b 0c 0d 0e 0f  |................|
+//	* 00000010  10 11 12 13                                      |....|              10 11 ff                                         |...|
+func HexDiff(want, got []byte) (diff string) {
+	if bytes.Equal(want, got) {
+		return
+	}
+
+	var buf strings.Builder
+	if len(want) != len(got) {
+		buf.WriteString(fmt.Sprintf("length mismatch: want %d bytes, got %d bytes\n", len(want), len(got)))
+	}
+
+	first := 0
+	for first < len(want) && first < len(got) && want[first] == got[first] {
+		first++
+	}
+	buf.WriteString(fmt.Sprintf("first difference at offset 0x%08x (%d)\n", first, first))
+
+	rows := (max(len(want), len(got)) + hexRowSize - 1) / hexRowSize
+	differs := make([]bool, rows)
+	for row := range differs {
+		start, end := row*hexRowSize, (row+1)*hexRowSize
+		differs[row] = !bytes.Equal(hexRow(want, start, end), hexRow(got, start, end))
+	}
+
+	// merge the differing rows, with context, into regions
+	var regions [][2]int
+	for row, different := range differs {
+		if !different {
+			continue
+		}
+		start, end := max(row-HexDiffContext, 0), min(row+HexDiffContext+1, rows)
+		if last := len(regions) - 1; last >= 0 && start <= regions[last][1] {
+			regions[last][1] = end
+		} else {
+			regions = append(regions, [2]int{start, end})
+		}
+	}
+
+	// the width of the hex and ascii columns, less the "want" label
+	pad := strings.Repeat(" ", len(hexColumns(nil))-4)
+	buf.WriteString("  offset    want" + pad + "  got\n")
+	for idx, region := range regions {
+		if idx >= HexDiffMaxRegions {
+			buf.WriteString(fmt.Sprintf("... and %d more differing regions\n", len(regions)-idx))
+			break
+		} else if idx > 0 {
+			buf.WriteString("  ...\n")
+		}
+		for row := region[0]; row < region[1]; row++ {
+			marker := " "
+			if differs[row] {
+				marker = "*"
+			}
+			start, end := row*hexRowSize, (row+1)*hexRowSize
+			buf.WriteString(fmt.Sprintf("%s %08x  %s  %s\n", marker, start, hexColumns(hexRow(want, start, end)), strings.TrimRight(hexColumns(hexRow(got, start, end)), " ")))
+		}
+	}
+	return buf.String()
+}
+
+func hexRow(data []byte, start, end int) (row []byte) {
+	if start >= len(data) {
+		return
+	}
+	return data[start:min(end, len(data))]
+}
+
+// hexColumns returns the fixed width hex and ASCII columns for a single row
+func hexColumns(row []byte) (columns string) {
+	var hex, ascii strings.Builder
+	for idx := 0; idx < hexRowSize; idx++ {
+		if idx > 0 {
+			hex.WriteString(" ")
+		}
+		if idx < len(row) {
+			hex.WriteString(fmt.Sprintf("%02x", row[idx]))
+			if c := row[idx]; c >= 0x20 && c < 0x7f {
+				ascii.WriteByte(c)
+			} else {
+				ascii.WriteByte('.')
+			}
+		} else {
+			hex.WriteString("  ")
+		}
+	}
+	text := "|" + ascii.String() + "|"
+	return hex.String() + "  " + text + strings.Repeat(" ", hexRowSize+2-len(text))
+}
+
+// GoldenBytes compares got with the contents of the named golden file
+// within td, failing the test with a HexDiff if they differ. When Updating,
+// the golden file is written with got instead. As with all golden helpers,
+// names ending with CompressedExtension are stored gzip compressed
+func GoldenBytes(t testing.TB, td TData, name string, got []byte) {
+	t.Helper()
+	golden(t, td, name, got, func(want []byte) (problem string) {
+		return HexDiff(want, got)
+	})
+}
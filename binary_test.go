@@ -0,0 +1,136 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHexDiff(t *testing.T) {
+
+	Convey("Equal", t, func() {
+		So(HexDiff(nil, nil), ShouldEqual, "")
+		So(HexDiff([]byte("same"), []byte("same")), ShouldEqual, "")
+	})
+
+	Convey("Length Mismatch", t, func() {
+		var want []byte
+		for idx := 0; idx < 20; idx++ {
+			want = append(want, byte(idx))
+		}
+		got := append([]byte{}, want[:19]...)
+		got[18] = 0xff
+		So(HexDiff(want, got), ShouldEqual, ""+
+			"length mismatch: want 20 bytes, got 19 bytes\n"+
+			"first difference at offset 0x00000012 (18)\n"+
+			"  offset    want                                                                 got\n"+
+			"  00000000  00 01 02 03 04 05 06 07 08 09 0a 0b 0c 0d 0e 0f  |................|  00 01 02 03 04 05 06 07 08 09 0a 0b 0c 0d 0e 0f  |................|\n"+
+			"* 00000010  10 11 12 13                                      |....|              10 11 ff                                         |...|\n",
+		)
+		So(HexDiff([]byte("ab"), []byte("abc")), ShouldStartWith, ""+
+			"length mismatch: want 2 bytes, got 3 bytes\n"+
+			"first difference at offset 0x00000002 (2)\n",
+		)
+	})
+
+	Convey("Regions", t, func() {
+		want, got := make([]byte, 200), make([]byte, 200)
+		got[5], got[150] = 'A', 'B'
+		diff := HexDiff(want, got)
+		lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+		So(lines, ShouldHaveLength, 8)
+		So(lines[0], ShouldEqual, "first difference at offset 0x00000005 (5)")
+		So(lines[2], ShouldStartWith, "* 00000000")
+		So(lines[2], ShouldEndWith, "|.....A..........|")
+		So(lines[3], ShouldStartWith, "  00000010")
+		So(lines[4], ShouldEqual, "  ...")
+		So(lines[5], ShouldStartWith, "  00000080")
+		So(lines[6], ShouldStartWith, "* 00000090")
+		So(lines[7], ShouldStartWith, "  000000a0")
+
+		defer func(max int) { HexDiffMaxRegions = max }(HexDiffMaxRegions)
+		HexDiffMaxRegions = 1
+		So(HexDiff(want, got), ShouldEndWith, "... and 1 more differing regions\n")
+	})
+
+}
+
+func TestGoldenBytes(t *testing.T) {
+
+	Convey("GoldenBytes", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		mt := newMockT("TestGoldenBytes")
+		withUpdating(func() {
+			GoldenBytes(mt, tmpd, "frame.bin", []byte{0xde, 0xad, 0xbe, 0xef})
+		})
+		So(mt.failed(), ShouldEqual, "")
+		So(tmpd.F("frame.bin"), ShouldEqual, "\xde\xad\xbe\xef")
+
+		mt = newMockT("TestGoldenBytes")
+		GoldenBytes(mt, tmpd, "frame.bin", []byte{0xde, 0xad, 0xbe, 0xef})
+		So(mt.failed(), ShouldEqual, "")
+
+		mt = newMockT("TestGoldenBytes")
+		GoldenBytes(mt, tmpd, "frame.bin", []byte{0xde, 0xad})
+		So(mt.failed(), ShouldStartWith, "golden file \"frame.bin\" mismatch:\n"+
+			"length mismatch: want 4 bytes, got 2 bytes\n"+
+			"first difference at offset 0x00000002 (2)\n")
+	})
+
+	Convey("Compressed", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		payload := bytes.Repeat([]byte("compressible "), 100)
+		mt := newMockT("TestGoldenBytes")
+		withUpdating(func() {
+			GoldenBytes(mt, tmpd, "large.bin.gz", payload)
+			Golden(mt, tmpd, "large.txt.gz", string(payload))
+		})
+		So(mt.failed(), ShouldEqual, "")
+
+		fh, err := os.Open(tmpd.Join("large.bin.gz"))
+		So(err, ShouldBeNil)
+		defer fh.Close()
+		gz, err := gzip.NewReader(fh)
+		So(err, ShouldBeNil)
+		data, err := io.ReadAll(gz)
+		So(err, ShouldBeNil)
+		So(data, ShouldEqual, payload)
+		So(len(tmpd.F("large.txt.gz")), ShouldBeLessThan, len(payload))
+
+		mt = newMockT("TestGoldenBytes")
+		GoldenBytes(mt, tmpd, "large.bin.gz", payload)
+		Golden(mt, tmpd, "large.txt.gz", string(payload))
+		So(mt.failed(), ShouldEqual, "")
+
+		So(os.WriteFile(tmpd.Join("corrupt.bin.gz"), []byte("not gzip"), 0644), ShouldBeNil)
+		mt = newMockT("TestGoldenBytes")
+		GoldenBytes(mt, tmpd, "corrupt.bin.gz", payload)
+		So(mt.failed(), ShouldStartWith, `error reading golden file "corrupt.bin.gz"`)
+	})
+
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	clPath "github.com/go-corelibs/path"
+)
+
+// CaseSkipMarker is the name of the file which, when present within a case
+// directory, skips that case with the file contents as the reason
+const CaseSkipMarker = "SKIP"
+
+var _ Case = (*testcase)(nil)
+
+// Case is a single fixture case directory given to the func of
+// CaseRunner.Run, all TData methods are scoped to the case directory
+type Case interface {
+	// Name returns the path of the case directory, relative to the TData
+	// Run was called on
+	Name() (name string)
+	// Golden is a convenience wrapper around the Golden func for the named
+	// golden file within the case directory, reporting to the case's test
+	Golden(name string, got string, options ...GoldenOption)
+
+	TData
+	FileOpener
+	CaseRunner
+}
+
+type testcase struct {
+	tdata
+
+	t    *testing.T
+	name string
+}
+
+func (c *testcase) Name() (name string) {
+	return c.name
+}
+
+func (c *testcase) Golden(name string, got string, options ...GoldenOption) {
+	c.t.Helper()
+	Golden(c.t, c, name, got, options...)
+}
+
+// Run runs fn as a subtest for each directory within td matching the
+// filepath.Match pattern, in lexical order. Each subtest is named after the
+// path of the directory, relative to td, so the usual `-run` filtering
+// applies to case names (for example: `-run 'TestParser/cases/empty'`).
+// Cases containing a CaseSkipMarker file are skipped and fn may call
+// t.Parallel to run the cases in parallel. Run fails the test if no
+// directories match the pattern
+func (td *tdata) Run(t *testing.T, pattern string, fn func(t *testing.T, c Case)) {
+	t.Helper()
+
+	matches, err := filepath.Glob(td.Join(pattern))
+	if err != nil {
+		t.Errorf("invalid case pattern %q: %v", pattern, err)
+		return
+	}
+	sort.Strings(matches)
+
+	var found bool
+	for _, match := range matches {
+		if !clPath.IsDir(match) {
+			continue
+		}
+		found = true
+		name := filepath.ToSlash(td.prune(match))
+		t.Run(name, func(t *testing.T) {
+			noteGuardTest(t)
+			checkChecksums(t, td)
+			c := &testcase{t: t, name: name}
+			c.path = match
+			if c.E(CaseSkipMarker) {
+				reason := strings.TrimSpace(c.F(CaseSkipMarker))
+				if reason == "" {
+					reason = "case has a " + CaseSkipMarker + " marker"
+				}
+				t.Skip(reason)
+			}
+			fn(t, c)
+		})
+	}
+	if !found {
+		t.Errorf("no case directories match %q", pattern)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCaseRun(t *testing.T) {
+
+	Convey("Run", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"cases/lower/input":   File("Hello"),
+			"cases/lower/output":  File("hello"),
+			"cases/upper/input":   File("Hello"),
+			"cases/upper/output":  File("HELLO"),
+			"cases/broken/input":  File("Hello"),
+			"cases/broken/SKIP":   File("not implemented yet\n"),
+			"cases/not-a-case.md": File("ignored"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		var names, paths, skipped []string
+		t.Run("sequential", func(t *testing.T) {
+			tmpd.Run(t, "cases/*", func(t *testing.T, c Case) {
+				names = append(names, t.Name())
+				paths = append(paths, c.Path())
+				got := strings.ToLower(c.F("input"))
+				if strings.HasSuffix(c.Name(), "upper") {
+					got = strings.ToUpper(c.F("input"))
+				}
+				c.Golden("output", got)
+			})
+		})
+		So(names, ShouldEqual, []string{
+			"TestCaseRun/sequential/cases/lower",
+			"TestCaseRun/sequential/cases/upper",
+		})
+		So(paths, ShouldEqual, []string{tmpd.Join("cases", "lower"), tmpd.Join("cases", "upper")})
+
+		var lock sync.Mutex
+		var parallel []string
+		t.Run("parallel", func(t *testing.T) {
+			tmpd.Run(t, "cases/*", func(t *testing.T, c Case) {
+				t.Parallel()
+				lock.Lock()
+				defer lock.Unlock()
+				parallel = append(parallel, c.Name())
+			})
+			t.Run("skips", func(t *testing.T) {
+				tmpd.Run(t, "cases/b*", func(t *testing.T, c Case) {
+					skipped = append(skipped, c.Name())
+				})
+			})
+		})
+		sort.Strings(parallel)
+		So(parallel, ShouldEqual, []string{"cases/lower", "cases/upper"})
+		So(skipped, ShouldBeEmpty)
+	})
+
+}
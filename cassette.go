@@ -0,0 +1,340 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// RedactedValue replaces the values of redacted headers in recorded
+// cassette interactions
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactedHeaders are the headers redacted from recorded cassette
+// interactions when the CassetteOptions RedactHeaders is nil
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// CassetteMatcher reports whether a recorded request matches the request
+// being made, the request bodies are given separately as they are consumed
+type CassetteMatcher func(recorded *http.Request, recordedBody []byte, r *http.Request, body []byte) (matched bool)
+
+// MatchMethod matches requests with the same method
+func MatchMethod(recorded *http.Request, _ []byte, r *http.Request, _ []byte) (matched bool) {
+	return recorded.Method == r.Method
+}
+
+// MatchURL matches requests with the same scheme, host, path and query
+// parameters, in any order
+func MatchURL(recorded *http.Request, _ []byte, r *http.Request, _ []byte) (matched bool) {
+	a, b := recorded.URL, r.URL
+	return a.Scheme == b.Scheme && a.Host == b.Host && a.Path == b.Path && reflect.DeepEqual(a.Query(), b.Query())
+}
+
+// MatchBody matches requests with the same body sha256 hash
+func MatchBody(_ *http.Request, recordedBody []byte, _ *http.Request, body []byte) (matched bool) {
+	return sha256Hex(recordedBody) == sha256Hex(body)
+}
+
+// DefaultCassetteMatchers are used when the CassetteOptions Match is nil
+var DefaultCassetteMatchers = []CassetteMatcher{MatchMethod, MatchURL}
+
+// CassetteOptions configures the behaviour of NewCassette
+type CassetteOptions struct {
+	// Match is the list of matchers which must all match for a recorded
+	// interaction to be replayed, DefaultCassetteMatchers when nil
+	Match []CassetteMatcher
+	// Repeat allows interactions to be replayed more than once, by default
+	// each recorded interaction is replayed once, in order
+	Repeat bool
+	// Record forces recording, which is also enabled when the cassette is
+	// within the scope of an update, except during a DryRun
+	Record bool
+	// Upstream is the base URL of the server requests are sent to when
+	// recording, typically a local stand-in for the real service. The
+	// scheme and host of each request are replaced with the Upstream's while
+	// the original URL is what is recorded
+	Upstream string
+	// Transport is used to send requests when recording, defaults to
+	// http.DefaultTransport
+	Transport http.RoundTripper
+	// RedactHeaders are the request and response headers with values
+	// replaced by RedactedValue when recording, DefaultRedactedHeaders when
+	// nil
+	RedactHeaders []string
+}
+
+type interaction struct {
+	name     string
+	fixture  *HTTPFixture
+	request  *http.Request
+	body     []byte
+	replayed bool
+}
+
+// Cassette is an http.RoundTripper which replays recorded HTTP interactions,
+// each stored as an HTTPFixture file within the cassette's directory (with
+// the absolute request URL in the request line). When recording, requests
+// are sent to the Upstream server instead and the cassette is rewritten
+// when the test completes
+type Cassette struct {
+	t            testing.TB
+	td           TData
+	name         string
+	opts         *CassetteOptions
+	upstream     *url.URL
+	recording    bool
+	interactions []*interaction
+
+	sync.Mutex
+}
+
+// NewCassette returns a Cassette for the named directory within td, failing
+// the test if the cassette cannot be loaded or, when recording, if there is
+// no Upstream server
+func NewCassette(t testing.TB, td TData, name string, opts *CassetteOptions) (c *Cassette) {
+	t.Helper()
+	if opts == nil {
+		opts = &CassetteOptions{}
+	}
+	c = &Cassette{t: t, td: td, name: name, opts: opts, recording: opts.Record || (updatingGolden(t, name) && !DryRun())}
+
+	if c.recording {
+		var err error
+		if opts.Upstream == "" {
+			t.Fatalf("cassette %q: recording requires an Upstream server", name)
+			return
+		} else if c.upstream, err = url.Parse(opts.Upstream); err != nil {
+			t.Fatalf("cassette %q: invalid Upstream: %v", name, err)
+			return
+		}
+		t.Cleanup(func() {
+			if err := c.save(); err != nil {
+				t.Errorf("error saving cassette %q: %v", name, err)
+			} else {
+				t.Logf("recorded cassette: %s (%d interactions)", name, len(c.interactions))
+			}
+		})
+		return
+	}
+
+	if !td.E(name) {
+		t.Fatalf("cassette %q not found, run with -%s to record it", name, UpdateFlag)
+		return
+	}
+	for _, path := range td.LF(name) {
+		if filepath.Ext(path) != HTTPFixtureExtension {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("error reading cassette %q: %v", name, err)
+			return
+		}
+		trackUsage(path)
+		it := &interaction{name: filepath.Base(path)}
+		if it.fixture, err = ParseHTTPFixture(data); err == nil {
+			it.request, err = it.fixture.NewRequest()
+		}
+		if err != nil {
+			t.Fatalf("error parsing cassette %q interaction %s: %v", name, it.name, err)
+			return
+		}
+		it.body, _ = io.ReadAll(it.request.Body)
+		c.interactions = append(c.interactions, it)
+	}
+	sort.Slice(c.interactions, func(i, j int) bool {
+		return c.interactions[i].name < c.interactions[j].name
+	})
+	return
+}
+
+// Recording returns true if the cassette is recording instead of replaying
+func (c *Cassette) Recording() (recording bool) {
+	return c.recording
+}
+
+// Client returns a new http.Client which uses the cassette as its Transport
+func (c *Cassette) Client() (client *http.Client) {
+	return &http.Client{Transport: c}
+}
+
+// RoundTrip replays the first matching recorded interaction or, when
+// recording, sends the request to the Upstream server and records it. A
+// request without a matching interaction fails the test, listing the
+// recorded interactions, and returns an ErrCassetteMiss error
+func (c *Cassette) RoundTrip(r *http.Request) (response *http.Response, err error) {
+	var body []byte
+	if r.Body != nil {
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return
+		}
+		_ = r.Body.Close()
+	}
+
+	if c.recording {
+		return c.record(r, body)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	matchers := c.opts.Match
+	if matchers == nil {
+		matchers = DefaultCassetteMatchers
+	}
+	for _, it := range c.interactions {
+		if it.replayed && !c.opts.Repeat {
+			continue
+		}
+		matched := true
+		for _, match := range matchers {
+			if matched = match(it.request, it.body, r, body); !matched {
+				break
+			}
+		}
+		if matched {
+			it.replayed = true
+			return &http.Response{
+				Status:        fmt.Sprintf("%d %s", it.fixture.Status, http.StatusText(it.fixture.Status)),
+				StatusCode:    it.fixture.Status,
+				Proto:         "HTTP/1.1",
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        it.fixture.Header.Clone(),
+				Body:          io.NopCloser(bytes.NewReader(it.fixture.Body)),
+				ContentLength: int64(len(it.fixture.Body)),
+				Request:       r,
+			}, nil
+		}
+	}
+
+	var listing []string
+	for _, it := range c.interactions {
+		state := ""
+		if it.replayed {
+			state = " (replayed)"
+		}
+		listing = append(listing, fmt.Sprintf("  %s: %s%s", it.name, describeRequest(it.request, it.body), state))
+	}
+	if len(listing) == 0 {
+		listing = append(listing, "  (none)")
+	}
+	c.t.Errorf("cassette %q has no interaction for:\n  %s\nrecorded interactions:\n%s", c.name, describeRequest(r, body), strings.Join(listing, "\n"))
+	err = fmt.Errorf("%w: %s", ErrCassetteMiss, describeRequest(r, body))
+	return
+}
+
+func describeRequest(r *http.Request, body []byte) (description string) {
+	description = r.Method + " " + r.URL.String()
+	if len(body) > 0 {
+		description += fmt.Sprintf(" (body sha256 %s)", sha256Hex(body)[:12])
+	}
+	return
+}
+
+func (c *Cassette) record(r *http.Request, body []byte) (response *http.Response, err error) {
+	outgoing := r.Clone(r.Context())
+	outgoing.URL.Scheme, outgoing.URL.Host, outgoing.Host = c.upstream.Scheme, c.upstream.Host, ""
+	outgoing.RequestURI = ""
+	outgoing.Body = io.NopCloser(bytes.NewReader(body))
+	outgoing.ContentLength = int64(len(body))
+
+	transport := c.opts.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if response, err = transport.RoundTrip(outgoing); err != nil {
+		return
+	}
+	var data []byte
+	data, err = io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return
+	}
+	response.Body = io.NopCloser(bytes.NewReader(data))
+	response.Request = r
+
+	var request strings.Builder
+	request.WriteString(r.Method + " " + r.URL.String() + " HTTP/1.1\n")
+	writeSortedHeader(&request, c.redact(r.Header))
+	head := trimLineEnding(request.String())
+	if len(body) > 0 {
+		head += "\n\n" + string(body)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.interactions = append(c.interactions, &interaction{
+		name: fmt.Sprintf("%03d-%s%s", len(c.interactions)+1, strings.ToLower(r.Method), HTTPFixtureExtension),
+		fixture: &HTTPFixture{
+			Request:  head,
+			Expected: true,
+			Status:   response.StatusCode,
+			Header:   c.redact(response.Header),
+			Body:     data,
+		},
+	})
+	return
+}
+
+func (c *Cassette) redact(header http.Header) (redacted http.Header) {
+	redacted = header.Clone()
+	names := c.opts.RedactHeaders
+	if names == nil {
+		names = DefaultRedactedHeaders
+	}
+	for _, name := range names {
+		values := redacted[http.CanonicalHeaderKey(name)]
+		for idx := range values {
+			values[idx] = RedactedValue
+		}
+	}
+	return
+}
+
+// save replaces the cassette's interaction files with those recorded
+func (c *Cassette) save() (err error) {
+	c.Lock()
+	defer c.Unlock()
+	defer lockPath(c.td.Join(c.name))()
+	if err = os.MkdirAll(c.td.Join(c.name), 0755); err != nil {
+		return
+	}
+	for _, path := range c.td.LF(c.name) {
+		if filepath.Ext(path) == HTTPFixtureExtension {
+			if err = os.Remove(path); err != nil {
+				return
+			}
+		}
+	}
+	for _, it := range c.interactions {
+		if err = writeFileAtomic(c.td.Join(c.name, it.name), it.fixture.Bytes(), 0644); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCassette(t *testing.T) {
+
+	do := func(client *http.Client, method, url, body string) (status int, data string, err error) {
+		request, _ := http.NewRequest(method, url, strings.NewReader(body))
+		request.Header.Set("Authorization", "Bearer secret")
+		var response *http.Response
+		if response, err = client.Do(request); err != nil {
+			return
+		}
+		defer response.Body.Close()
+		raw, _ := io.ReadAll(response.Body)
+		return response.StatusCode, string(raw), nil
+	}
+
+	Convey("Record and Replay", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		var hosts []string
+		standIn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hosts = append(hosts, r.Host)
+			body, _ := io.ReadAll(r.Body)
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "private"})
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = fmt.Fprintf(w, "%s %s %s", r.Method, r.URL.RequestURI(), body)
+		}))
+		defer standIn.Close()
+
+		mt := newMockT("TestCassette")
+		cassette := NewCassette(mt, tmpd, "api", &CassetteOptions{Record: true, Upstream: standIn.URL})
+		So(cassette.Recording(), ShouldBeTrue)
+		status, body, err := do(cassette.Client(), "GET", "https://api.example.com/items?b=2&a=1", "")
+		So(err, ShouldBeNil)
+		So(status, ShouldEqual, http.StatusOK)
+		So(body, ShouldEqual, "GET /items?b=2&a=1 ")
+		_, body, err = do(cassette.Client(), "POST", "https://api.example.com/items", "one\n")
+		So(err, ShouldBeNil)
+		So(body, ShouldEqual, "POST /items one\n")
+		So(hosts, ShouldEqual, []string{strings.TrimPrefix(standIn.URL, "http://"), strings.TrimPrefix(standIn.URL, "http://")})
+		mt.runCleanup()
+		So(mt.failed(), ShouldEqual, "")
+
+		So(tmpd.LF("api"), ShouldEqual, []string{tmpd.Join("api", "001-get.http"), tmpd.Join("api", "002-post.http")})
+		recorded := tmpd.F("api/002-post.http")
+		So(recorded, ShouldStartWith, ""+
+			"POST https://api.example.com/items HTTP/1.1\n"+
+			"Authorization: [REDACTED]\n")
+		So(recorded, ShouldContainSubstring, "\n\none\n\n###\nHTTP/1.1 200 OK\n")
+		So(recorded, ShouldContainSubstring, "Set-Cookie: [REDACTED]\n")
+		So(recorded, ShouldEndWith, "\n\nPOST /items one\n")
+		So(recorded, ShouldNotContainSubstring, "secret")
+		So(recorded, ShouldNotContainSubstring, "private")
+
+		// replay, in any order, with the query parameters in any order
+		mt = newMockT("TestCassette")
+		cassette = NewCassette(mt, tmpd, "api", &CassetteOptions{Match: []CassetteMatcher{MatchMethod, MatchURL, MatchBody}})
+		So(cassette.Recording(), ShouldBeFalse)
+		_, body, err = do(cassette.Client(), "POST", "https://api.example.com/items", "one\n")
+		So(err, ShouldBeNil)
+		So(body, ShouldEqual, "POST /items one\n")
+		status, body, err = do(cassette.Client(), "GET", "https://api.example.com/items?a=1&b=2", "")
+		So(err, ShouldBeNil)
+		So(status, ShouldEqual, http.StatusOK)
+		So(body, ShouldEqual, "GET /items?b=2&a=1 ")
+		So(mt.failed(), ShouldEqual, "")
+
+		// each interaction replays once
+		_, _, err = do(cassette.Client(), "GET", "https://api.example.com/items?a=1&b=2", "")
+		So(errors.Is(err, ErrCassetteMiss), ShouldBeTrue)
+		So(mt.failed(), ShouldEqual, ""+
+			"cassette \"api\" has no interaction for:\n"+
+			"  GET https://api.example.com/items?a=1&b=2\n"+
+			"recorded interactions:\n"+
+			"  001-get.http: GET https://api.example.com/items?b=2&a=1 (replayed)\n"+
+			"  002-post.http: POST https://api.example.com/items (body sha256 "+sha256Hex([]byte("one\n"))[:12]+") (replayed)",
+		)
+
+		// body matching
+		mt = newMockT("TestCassette")
+		cassette = NewCassette(mt, tmpd, "api", &CassetteOptions{Match: []CassetteMatcher{MatchMethod, MatchURL, MatchBody}, Repeat: true})
+		_, _, err = do(cassette.Client(), "POST", "https://api.example.com/items", "two\n")
+		So(errors.Is(err, ErrCassetteMiss), ShouldBeTrue)
+		for idx := 0; idx < 2; idx++ {
+			_, _, err = do(cassette.Client(), "GET", "https://api.example.com/items?a=1&b=2", "")
+			So(err, ShouldBeNil)
+		}
+	})
+
+	Convey("Errors", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		mt := newMockT("TestCassette")
+		NewCassette(mt, tmpd, "missing", nil)
+		So(mt.fatal, ShouldBeTrue)
+		So(mt.failed(), ShouldEqual, "cassette \"missing\" not found, run with -tdata.update to record it")
+
+		mt = newMockT("TestCassette")
+		withUpdating(func() {
+			NewCassette(mt, tmpd, "missing", nil)
+		})
+		So(mt.fatal, ShouldBeTrue)
+		So(mt.failed(), ShouldEqual, "cassette \"missing\": recording requires an Upstream server")
+	})
+
+}
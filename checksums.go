@@ -0,0 +1,322 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	// ChecksumsFile is the name of the checksums file, within the top-level
+	// of a TData, which lists the SHA-256 sums of the fixture files in the
+	// same format as the `sha256sum` command
+	ChecksumsFile = "SHA256SUMS"
+	// LFSPointerPrefix is the first line of every Git LFS pointer file
+	LFSPointerPrefix = "version https://git-lfs.github.com/spec/v1"
+	// lfsPointerMaxSize is the largest size of a Git LFS pointer file
+	lfsPointerMaxSize = 1024
+)
+
+// Checksums maps slash-separated file paths, relative to a TData, to the
+// hex-encoded SHA-256 sum of their contents
+type Checksums map[string]string
+
+// ParseChecksums parses the text of a ChecksumsFile, which is one file per
+// line, each with the sum, two spaces (or a space and an asterisk) and the
+// path. Empty lines and lines starting with a "#" are ignored
+func ParseChecksums(text string) (sums Checksums, err error) {
+	sums = make(Checksums)
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sum, path, ok := strings.Cut(line, " ")
+		if ok && (strings.HasPrefix(path, " ") || strings.HasPrefix(path, "*")) {
+			path = path[1:]
+		} else {
+			ok = false
+		}
+		if _, ee := hex.DecodeString(sum); !ok || ee != nil || len(sum) != sha256.Size*2 || path == "" {
+			err = fmt.Errorf("%w: line %d: expected a sha256 sum and a path", ErrChecksumsSyntax, lineNo)
+			return
+		}
+		sums[path] = strings.ToLower(sum)
+	}
+	err = scanner.Err()
+	return
+}
+
+// String returns the ChecksumsFile text, sorted by path
+func (sums Checksums) String() (text string) {
+	paths := make([]string, 0, len(sums))
+	for path := range sums {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var buf strings.Builder
+	for _, path := range paths {
+		buf.WriteString(sums[path] + "  " + path + "\n")
+	}
+	return buf.String()
+}
+
+// ReadChecksums reads the ChecksumsFile of td
+func ReadChecksums(td TData) (sums Checksums, err error) {
+	var data []byte
+	if data, err = os.ReadFile(filepath.Join(td.Path(), ChecksumsFile)); err != nil {
+		return
+	}
+	return ParseChecksums(string(data))
+}
+
+// GenerateChecksums returns the Checksums of every regular file within td,
+// including hidden files and excluding the ChecksumsFile itself and any
+// PendingIndexFile
+func GenerateChecksums(td TData) (sums Checksums, err error) {
+	sums = make(Checksums)
+	err = walkChecksumFiles(td, func(rel, path string, size int64) (err error) {
+		sums[rel], err = sha256File(path)
+		return
+	})
+	return
+}
+
+// WriteChecksums writes the sums to the ChecksumsFile of td
+func WriteChecksums(td TData, sums Checksums) (err error) {
+	return writeFileAtomic(filepath.Join(td.Path(), ChecksumsFile), []byte(sums.String()), 0644)
+}
+
+// UpdateChecksums rewrites the ChecksumsFile of td. Without any paths given,
+// the file lists every file within td, as with GenerateChecksums. With paths
+// given, only the entries for those slash-separated paths are updated (or
+// removed, if the file no longer exists) and the other entries are kept
+func UpdateChecksums(td TData, paths ...string) (err error) {
+	var sums Checksums
+	if len(paths) == 0 {
+		if sums, err = GenerateChecksums(td); err != nil {
+			return
+		}
+		return WriteChecksums(td, sums)
+	}
+
+	if sums, err = ReadChecksums(td); errors.Is(err, fs.ErrNotExist) {
+		sums, err = make(Checksums), nil
+	} else if err != nil {
+		return
+	}
+	for _, path := range paths {
+		var sum string
+		if sum, err = sha256File(filepath.Join(td.Path(), filepath.FromSlash(path))); errors.Is(err, fs.ErrNotExist) {
+			delete(sums, path)
+		} else if err != nil {
+			return
+		} else {
+			sums[path] = sum
+		}
+	}
+	return WriteChecksums(td, sums)
+}
+
+// VerifyChecksums checks the files within td against its ChecksumsFile,
+// returning an error for each file which is missing (wrapping
+// fs.ErrNotExist) or which has different contents (wrapping
+// ErrChecksumMismatch). All files, listed or not, are also checked for being
+// Git LFS pointers (wrapping ErrLFSPointer), which happens when a checkout
+// was made without fetching the LFS objects. A td without a ChecksumsFile is
+// only checked for LFS pointers. Multiple errors are combined with
+// errors.Join
+func VerifyChecksums(td TData) (err error) {
+	var sums Checksums
+	if sums, err = ReadChecksums(td); errors.Is(err, fs.ErrNotExist) {
+		sums, err = make(Checksums), nil
+	} else if err != nil {
+		return
+	}
+
+	var errs []error
+	seen := make(map[string]struct{})
+	err = walkChecksumFiles(td, func(rel, path string, size int64) (err error) {
+		seen[rel] = struct{}{}
+		if size <= lfsPointerMaxSize {
+			var data []byte
+			if data, err = os.ReadFile(path); err != nil {
+				return
+			} else if IsLFSPointer(data) {
+				errs = append(errs, fmt.Errorf("%w: %s, run `git lfs pull` to fetch the file contents", ErrLFSPointer, rel))
+				return
+			}
+		}
+		if want, listed := sums[rel]; listed {
+			var got string
+			if got, err = sha256File(path); err != nil {
+				return
+			} else if got != want {
+				errs = append(errs, fmt.Errorf("%w: %s", ErrChecksumMismatch, rel))
+			}
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	var missing []string
+	for rel := range sums {
+		if _, present := seen[rel]; !present {
+			missing = append(missing, rel)
+		}
+	}
+	sort.Strings(missing)
+	for _, rel := range missing {
+		errs = append(errs, fmt.Errorf("%w: %s", fs.ErrNotExist, rel))
+	}
+	return errors.Join(errs...)
+}
+
+// IsLFSPointer reports whether the data is the contents of a Git LFS pointer
+// file rather than the file it points to
+func IsLFSPointer(data []byte) (pointer bool) {
+	return len(data) <= lfsPointerMaxSize && bytes.HasPrefix(data, []byte(LFSPointerPrefix+"\n"))
+}
+
+var verifiedChecksums sync.Map
+
+// verifyOutput is where verifyChecksumsOnce reports the problems found
+var verifyOutput io.Writer = os.Stderr
+
+// verifyChecksumsOnce runs VerifyChecksums on the root, which checks for Git
+// LFS pointers whether or not the root has a ChecksumsFile, returning the
+// same result for every call with the root. Problems are reported to the
+// verifyOutput as soon as they are found, as well as by checkChecksums and
+// Main later on
+func verifyChecksumsOnce(root string) (err error) {
+	if result, present := verifiedChecksums.Load(root); present {
+		err, _ = result.(error)
+		return
+	}
+	err = VerifyChecksums(&tdata{path: root})
+	if result, loaded := verifiedChecksums.LoadOrStore(root, err); loaded {
+		err, _ = result.(error)
+	} else if err != nil {
+		_, _ = fmt.Fprintf(verifyOutput, "tdata: fixture files in %s failed verification:\n%v\n", root, err)
+	}
+	return
+}
+
+// checkChecksums fails the test when the verification of td, made by
+// verifyChecksumsOnce when the TestData was constructed, found problems. The
+// tdata helpers which are given a testing.TB call this on behalf of their
+// callers
+func checkChecksums(t testing.TB, td TData) {
+	t.Helper()
+	if result, present := verifiedChecksums.Load(td.Path()); present {
+		if err, _ := result.(error); err != nil {
+			t.Fatalf("fixture files in %s failed verification:\n%v", td.Path(), err)
+		}
+	}
+}
+
+// writeChecksumFailures prints each of the TestData directories which failed
+// verification to w, returning true if there were any
+func writeChecksumFailures(w io.Writer) (failed bool) {
+	var roots []string
+	verifiedChecksums.Range(func(key, value any) bool {
+		if err, _ := value.(error); err != nil {
+			roots = append(roots, key.(string))
+		}
+		return true
+	})
+	sort.Strings(roots)
+	for _, root := range roots {
+		result, _ := verifiedChecksums.Load(root)
+		_, _ = fmt.Fprintf(w, "tdata: fixture files in %s failed verification:\n%v\n", root, result)
+	}
+	return len(roots) > 0
+}
+
+// checksumsMutex serializes the updates made by refreshChecksums
+var checksumsMutex sync.Mutex
+
+// refreshChecksums updates the entries of the named files within the
+// ChecksumsFile of td, if it has one, after the files were written
+func refreshChecksums(td TData, names ...string) (err error) {
+	if len(names) == 0 {
+		return
+	} else if _, err = os.Stat(filepath.Join(td.Path(), ChecksumsFile)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+	paths := make([]string, len(names))
+	for idx, name := range names {
+		paths[idx] = filepath.ToSlash(name)
+	}
+	checksumsMutex.Lock()
+	defer checksumsMutex.Unlock()
+	return UpdateChecksums(td, paths...)
+}
+
+// walkChecksumFiles calls fn with the slash-separated relative path, the
+// absolute path and the size of each regular file within td, in lexical
+// order, excluding the ChecksumsFile and the PendingIndexFile
+func walkChecksumFiles(td TData, fn func(rel, path string, size int64) (err error)) (err error) {
+	root := td.Path()
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		if rel = filepath.ToSlash(rel); rel == ChecksumsFile || rel == PendingIndexFile {
+			return nil
+		}
+		var info fs.FileInfo
+		if info, err = d.Info(); err != nil {
+			return err
+		}
+		return fn(rel, path, info.Size())
+	})
+}
+
+// sha256File returns the hex-encoded SHA-256 sum of the file contents
+func sha256File(path string) (sum string, err error) {
+	var fh *os.File
+	if fh, err = os.Open(path); err != nil {
+		return
+	}
+	defer func() { _ = fh.Close() }()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, fh); err == nil {
+		sum = hex.EncodeToString(hash.Sum(nil))
+	}
+	return
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const lfsPointer = LFSPointerPrefix + "\n" +
+	"oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\n" +
+	"size 12345\n"
+
+func TestChecksums(t *testing.T) {
+
+	Convey("Parse and String", t, func() {
+		sums, err := ParseChecksums("" +
+			"# comment\n" +
+			"\n" +
+			"2CF24DBA5FB0A30E26E83B2AC5B9E29E1B161E5C1FA7425E73043362938B9824  hello.txt\r\n" +
+			"486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7 *binary/world.bin\n",
+		)
+		So(err, ShouldBeNil)
+		So(sums, ShouldResemble, Checksums{
+			"hello.txt":        "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			"binary/world.bin": "486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7",
+		})
+		So(sums.String(), ShouldEqual, ""+
+			"486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7  binary/world.bin\n"+
+			"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  hello.txt\n",
+		)
+
+		for _, text := range []string{
+			"2cf24dba  hello.txt\n",
+			"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824\n",
+			"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824 hello.txt\n",
+			"zzf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  hello.txt\n",
+			"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  \n",
+		} {
+			_, err = ParseChecksums(text)
+			So(errors.Is(err, ErrChecksumsSyntax), ShouldBeTrue)
+		}
+	})
+
+	Convey("Generate, Update and Verify", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"hello.txt":        File("hello"),
+			"binary/world.bin": File("world"),
+			".hidden":          File("hidden"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		// nothing to verify
+		So(VerifyChecksums(tmpd), ShouldBeNil)
+
+		So(UpdateChecksums(tmpd), ShouldBeNil)
+		So(tmpd.F(ChecksumsFile), ShouldEqual, ""+
+			"e564b4081d7a9ea4b00dada53bdae70c99b87b6fce869f0c3dd4d2bfa1e53e1c  .hidden\n"+
+			"486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7  binary/world.bin\n"+
+			"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  hello.txt\n",
+		)
+		So(VerifyChecksums(tmpd), ShouldBeNil)
+
+		// unlisted files are fine
+		So(os.WriteFile(tmpd.Join("new.txt"), []byte("new"), 0644), ShouldBeNil)
+		So(VerifyChecksums(tmpd), ShouldBeNil)
+
+		So(os.WriteFile(tmpd.Join("hello.txt"), []byte("corrupted"), 0644), ShouldBeNil)
+		So(os.Remove(tmpd.Join(".hidden")), ShouldBeNil)
+		So(os.WriteFile(tmpd.Join("binary", "world.bin"), []byte(lfsPointer), 0644), ShouldBeNil)
+		err = VerifyChecksums(tmpd)
+		So(errors.Is(err, ErrChecksumMismatch), ShouldBeTrue)
+		So(errors.Is(err, ErrLFSPointer), ShouldBeTrue)
+		So(errors.Is(err, fs.ErrNotExist), ShouldBeTrue)
+		So(err.Error(), ShouldEqual, ""+
+			"git lfs pointer file: binary/world.bin, run `git lfs pull` to fetch the file contents\n"+
+			"checksum mismatch: hello.txt\n"+
+			"file does not exist: .hidden",
+		)
+
+		// partial updates
+		So(UpdateChecksums(tmpd, "hello.txt", ".hidden", "new.txt"), ShouldBeNil)
+		sums, err := ReadChecksums(tmpd)
+		So(err, ShouldBeNil)
+		So(sums, ShouldHaveLength, 3)
+		So(sums["new.txt"], ShouldEqual, sha256Hex([]byte("new")))
+		So(sums["hello.txt"], ShouldEqual, sha256Hex([]byte("corrupted")))
+		So(sums, ShouldContainKey, "binary/world.bin")
+		So(UpdateChecksums(tmpd, "missing/dir"), ShouldBeNil)
+
+		// unlisted pointers are detected too
+		So(os.Remove(tmpd.Join(ChecksumsFile)), ShouldBeNil)
+		So(UpdateChecksums(tmpd, "hello.txt"), ShouldBeNil)
+		So(tmpd.F(ChecksumsFile), ShouldStartWith, sha256Hex([]byte("corrupted"))+"  hello.txt\n")
+		So(errors.Is(VerifyChecksums(tmpd), ErrLFSPointer), ShouldBeTrue)
+
+		So(os.WriteFile(tmpd.Join(ChecksumsFile), []byte("bad\n"), 0644), ShouldBeNil)
+		So(errors.Is(VerifyChecksums(tmpd), ErrChecksumsSyntax), ShouldBeTrue)
+		So(errors.Is(UpdateChecksums(tmpd, "hello.txt"), ErrChecksumsSyntax), ShouldBeTrue)
+	})
+
+	Convey("IsLFSPointer", t, func() {
+		So(IsLFSPointer([]byte(lfsPointer)), ShouldBeTrue)
+		So(IsLFSPointer([]byte(LFSPointerPrefix)), ShouldBeFalse)
+		So(IsLFSPointer([]byte("hello")), ShouldBeFalse)
+		So(IsLFSPointer([]byte(lfsPointer+strings.Repeat("x", 1024))), ShouldBeFalse)
+	})
+
+	Convey("Verified Once", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{"data.bin": File("data")}.Apply(tmpd), ShouldBeNil)
+		var reported strings.Builder
+		defer func(previous io.Writer) { verifyOutput = previous }(verifyOutput)
+		verifyOutput = &reported
+		So(verifyChecksumsOnce(tmpd.Path()), ShouldBeNil)
+
+		So(UpdateChecksums(tmpd), ShouldBeNil)
+		So(os.WriteFile(tmpd.Join("data.bin"), []byte("changed"), 0644), ShouldBeNil)
+		// cached from the first call
+		So(verifyChecksumsOnce(tmpd.Path()), ShouldBeNil)
+		verifiedChecksums.Delete(tmpd.Path())
+		So(errors.Is(verifyChecksumsOnce(tmpd.Path()), ErrChecksumMismatch), ShouldBeTrue)
+		So(errors.Is(verifyChecksumsOnce(tmpd.Path()), ErrChecksumMismatch), ShouldBeTrue)
+		// reported once, as soon as it was found
+		So(reported.String(), ShouldEqual, "tdata: fixture files in "+tmpd.Path()+" failed verification:\nchecksum mismatch: data.bin\n")
+
+		// reported through the tests and by Main instead of panicking
+		mt := newMockT("TestVerified")
+		Golden(mt, tmpd, "data.bin", "changed")
+		So(mt.fatal, ShouldBeTrue)
+		So(mt.failed(), ShouldEqual, "fixture files in "+tmpd.Path()+" failed verification:\nchecksum mismatch: data.bin")
+		var output strings.Builder
+		So(writeChecksumFailures(&output), ShouldBeTrue)
+		So(output.String(), ShouldContainSubstring, "tdata: fixture files in "+tmpd.Path()+" failed verification:\nchecksum mismatch: data.bin\n")
+		verifiedChecksums.Delete(tmpd.Path())
+		output.Reset()
+		So(writeChecksumFailures(&output), ShouldBeFalse)
+		So(output.String(), ShouldEqual, "")
+
+		// LFS pointers are found without a ChecksumsFile
+		So(os.Remove(tmpd.Join(ChecksumsFile)), ShouldBeNil)
+		So(os.WriteFile(tmpd.Join("data.bin"), []byte(lfsPointer), 0644), ShouldBeNil)
+		reported.Reset()
+		So(errors.Is(verifyChecksumsOnce(tmpd.Path()), ErrLFSPointer), ShouldBeTrue)
+		So(reported.String(), ShouldStartWith, "tdata: fixture files in "+tmpd.Path()+" failed verification:\ngit lfs pointer file: data.bin")
+		verifiedChecksums.Delete(tmpd.Path())
+	})
+
+	Convey("Refreshed", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"out.golden":       File("old\n"),
+			"other.golden":     File("other\n"),
+			"other.golden.new": File("accepted\n"),
+		}.Apply(tmpd), ShouldBeNil)
+		So(UpdateChecksums(tmpd), ShouldBeNil)
+
+		withUpdating(func() {
+			mt := newMockT("TestRefreshed")
+			Golden(mt, tmpd, "out.golden", "new\n")
+			Golden(mt, tmpd, "nested/created.golden", "created\n")
+			So(mt.failed(), ShouldEqual, "")
+		})
+		accepted, err := AcceptPending(tmpd)
+		So(err, ShouldBeNil)
+		So(accepted, ShouldEqual, []string{"other.golden"})
+		So(VerifyChecksums(tmpd), ShouldBeNil)
+		sums, err := ReadChecksums(tmpd)
+		So(err, ShouldBeNil)
+		So(sums, ShouldContainKey, "nested/created.golden")
+		So(sums, ShouldNotContainKey, "other.golden.new")
+
+		// without a ChecksumsFile there is nothing to refresh
+		So(os.Remove(tmpd.Join(ChecksumsFile)), ShouldBeNil)
+		So(refreshChecksums(tmpd, "out.golden"), ShouldBeNil)
+		So(tmpd.E(ChecksumsFile), ShouldBeFalse)
+	})
+
+}
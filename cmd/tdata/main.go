@@ -0,0 +1,344 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command tdata curates the test data directory of a Go module, finding it
+// the same way as tdata.NewNamed does for the package in the current (or -C)
+// directory
+//
+//	Usage: tdata [-C dir] [-name testdata] <command> [arguments]
+//
+//	Commands:
+//	  ls [-r] [-f|-d] [-H] [dirname]  list fixtures, as the TData L* methods
+//	  verify [-update] [names...]     check the SHA256SUMS and for LFS pointers
+//	  pack [-o file] [dirname]        write a txtar archive of the dirname
+//	  unpack [-f] [-d dirname] file   extract a txtar archive, "-" for stdin
+//	  lint [-skip checks] [-max-*]    report problems with the fixtures
+//	  pending [-diff] [names...]      list pending golden files, with differences
+//	  accept [names...]               promote pending golden files
+//	  reject [names...]               remove pending golden files
+//
+// The exit status is 1 when problems are found and 2 for usage and other
+// errors
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-corelibs/tdata"
+)
+
+const usage = `Usage: tdata [-C dir] [-name testdata] <command> [arguments]
+
+Commands:
+  ls [-r] [-f|-d] [-H] [dirname]  list fixtures, as the TData L* methods
+  verify [-update] [names...]     check the SHA256SUMS and for LFS pointers
+  pack [-o file] [dirname]        write a txtar archive of the dirname
+  unpack [-f] [-d dirname] file   extract a txtar archive, "-" for stdin
+  lint [-skip checks] [-max-*]    report problems with the fixtures
+  pending [-diff] [names...]      list pending golden files, with differences
+  accept [names...]               promote pending golden files
+  reject [names...]               remove pending golden files
+`
+
+type command func(c *cli, td tdata.TestData, args []string) (code int)
+
+var commands = map[string]command{
+	"ls":      (*cli).ls,
+	"verify":  (*cli).verify,
+	"pack":    (*cli).pack,
+	"unpack":  (*cli).unpack,
+	"lint":    (*cli).lint,
+	"pending": (*cli).pending,
+	"accept":  (*cli).accept,
+	"reject":  (*cli).reject,
+}
+
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	c := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(c.run(os.Args[1:]))
+}
+
+func (c *cli) run(args []string) (code int) {
+	fs := c.flagSet("tdata")
+	fs.Usage = func() { _, _ = fmt.Fprint(c.stderr, usage) }
+	dir := fs.String("C", ".", "the Go package directory")
+	name := fs.String("name", tdata.DefaultTestData, "the test data directory name")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	} else if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		c.errorf("unknown command %q", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+	td, err := tdata.Load(*dir, *name)
+	if err != nil {
+		c.errorf("%v", err)
+		return 2
+	}
+	return cmd(c, td, fs.Args()[1:])
+}
+
+func (c *cli) flagSet(name string) (fs *flag.FlagSet) {
+	fs = flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return
+}
+
+func (c *cli) errorf(format string, argv ...any) {
+	_, _ = fmt.Fprintf(c.stderr, "tdata: "+format+"\n", argv...)
+}
+
+func (c *cli) printf(format string, argv ...any) {
+	_, _ = fmt.Fprintf(c.stdout, format, argv...)
+}
+
+// relative returns the slash-separated path relative to td
+func relative(td tdata.TData, path string) (rel string) {
+	rel, _ = filepath.Rel(td.Path(), path)
+	return filepath.ToSlash(rel)
+}
+
+func (c *cli) ls(td tdata.TestData, args []string) (code int) {
+	fs := c.flagSet("ls")
+	recursive := fs.Bool("r", false, "list recursively")
+	files := fs.Bool("f", false, "list only files")
+	dirs := fs.Bool("d", false, "list only directories")
+	hidden := fs.Bool("H", false, "include hidden files")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	} else if fs.NArg() > 1 || (*files && *dirs) {
+		c.errorf("usage: ls [-r] [-f|-d] [-H] [dirname]")
+		return 2
+	}
+	dirname := fs.Arg(0)
+
+	var list func(dirname string) (found []string)
+	switch {
+	case !*recursive && (*files || *dirs) && *hidden:
+		c.errorf("ls: -H requires -r when listing only files or directories")
+		return 2
+	case !*recursive && *files:
+		list = td.LF
+	case !*recursive && *dirs:
+		list = td.LD
+	case !*recursive && *hidden:
+		list = td.LH
+	case !*recursive:
+		list = td.L
+	case *files && *hidden:
+		list = td.LAFH
+	case *files:
+		list = td.LAF
+	case *dirs && *hidden:
+		list = td.LADH
+	case *dirs:
+		list = td.LAD
+	case *hidden:
+		list = td.LAH
+	default:
+		list = td.LA
+	}
+	for _, found := range list(dirname) {
+		c.printf("%s\n", relative(td, found))
+	}
+	return
+}
+
+func (c *cli) verify(td tdata.TestData, args []string) (code int) {
+	fs := c.flagSet("verify")
+	update := fs.Bool("update", false, "update the checksums of the names given, or of all files")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *update {
+		if err := tdata.UpdateChecksums(td, fs.Args()...); err != nil {
+			c.errorf("%v", err)
+			return 2
+		}
+		c.printf("updated %s\n", tdata.ChecksumsFile)
+		return
+	} else if fs.NArg() > 0 {
+		c.errorf("usage: verify [-update] [names...]")
+		return 2
+	}
+
+	if err := tdata.VerifyChecksums(td); err != nil {
+		if errors.Is(err, tdata.ErrChecksumsSyntax) {
+			c.errorf("%v", err)
+			return 2
+		}
+		for _, line := range strings.Split(err.Error(), "\n") {
+			c.printf("%s\n", line)
+		}
+		return 1
+	}
+	return
+}
+
+func (c *cli) pack(td tdata.TestData, args []string) (code int) {
+	fs := c.flagSet("pack")
+	output := fs.String("o", "-", "the file to write, \"-\" for stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	} else if fs.NArg() > 1 {
+		c.errorf("usage: pack [-o file] [dirname]")
+		return 2
+	}
+	data, err := tdata.PackTxtar(td, fs.Arg(0))
+	if err == nil {
+		if *output == "-" {
+			_, err = c.stdout.Write(data)
+		} else {
+			err = os.WriteFile(*output, data, 0644)
+		}
+	}
+	if err != nil {
+		c.errorf("%v", err)
+		return 2
+	}
+	return
+}
+
+func (c *cli) unpack(td tdata.TestData, args []string) (code int) {
+	fs := c.flagSet("unpack")
+	force := fs.Bool("f", false, "overwrite existing files")
+	dirname := fs.String("d", "", "the directory, within the test data, to extract to")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	} else if fs.NArg() != 1 {
+		c.errorf("usage: unpack [-f] [-d dirname] file")
+		return 2
+	}
+
+	var data []byte
+	var err error
+	if fs.Arg(0) == "-" {
+		data, err = io.ReadAll(c.stdin)
+	} else {
+		data, err = os.ReadFile(fs.Arg(0))
+	}
+	var written []string
+	if err == nil {
+		written, err = tdata.UnpackTxtar(td, *dirname, data, *force)
+	}
+	if err != nil {
+		c.errorf("%v", err)
+		return 2
+	}
+	for _, name := range written {
+		c.printf("%s\n", name)
+	}
+	return
+}
+
+func (c *cli) lint(td tdata.TestData, args []string) (code int) {
+	fs := c.flagSet("lint")
+	skip := fs.String("skip", "", "comma-separated names of checks to skip")
+	maxPath := fs.Int("max-path", tdata.DefaultLintMaxPathLength, "the longest path allowed, in characters")
+	maxSize := fs.Int64("max-size", tdata.DefaultLintMaxFileSize, "the largest file size allowed, in bytes, negative for any size")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	} else if fs.NArg() > 0 {
+		c.errorf("usage: lint [-skip checks] [-max-path n] [-max-size n]")
+		return 2
+	}
+	opts := &tdata.LintOptions{MaxPathLength: *maxPath, MaxFileSize: *maxSize}
+	if *skip != "" {
+		opts.Skip = strings.Split(*skip, ",")
+	}
+	issues, err := tdata.Lint(td, opts)
+	if err != nil {
+		c.errorf("%v", err)
+		return 2
+	}
+	for _, issue := range issues {
+		c.printf("%s\n", issue)
+	}
+	if len(issues) > 0 {
+		return 1
+	}
+	return
+}
+
+func (c *cli) accept(td tdata.TestData, args []string) (code int) {
+	accepted, err := tdata.AcceptPending(td, args...)
+	for _, name := range accepted {
+		c.printf("accepted %s\n", name)
+	}
+	if err != nil {
+		c.errorf("%v", err)
+		return 2
+	}
+	return
+}
+
+func (c *cli) pending(td tdata.TestData, args []string) (code int) {
+	fs := c.flagSet("pending")
+	diff := fs.Bool("diff", false, "show the differences of each pending golden file")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	names := fs.Args()
+	if len(names) == 0 {
+		var err error
+		if names, err = tdata.PendingFiles(td); err != nil {
+			c.errorf("%v", err)
+			return 2
+		}
+	}
+	for _, name := range names {
+		name = strings.TrimSuffix(name, tdata.PendingExtension)
+		if !*diff {
+			c.printf("%s\n", name)
+			continue
+		}
+		text, err := tdata.PendingDiff(td, name)
+		if err != nil {
+			c.errorf("%v", err)
+			return 2
+		}
+		c.printf("%s", text)
+	}
+	return
+}
+
+func (c *cli) reject(td tdata.TestData, args []string) (code int) {
+	rejected, err := tdata.RejectPending(td, args...)
+	for _, name := range rejected {
+		c.printf("rejected %s\n", name)
+	}
+	if err != nil {
+		c.errorf("%v", err)
+		return 2
+	}
+	return
+}
@@ -0,0 +1,274 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/go-corelibs/tdata"
+)
+
+// runCLI runs the command line given within the module directory of tmpd,
+// returning the exit code, stdout and stderr
+func runCLI(tmpd tdata.TempData, stdin string, args ...string) (code int, stdout, stderr string) {
+	var out, errs strings.Builder
+	c := &cli{stdin: strings.NewReader(stdin), stdout: &out, stderr: &errs}
+	code = c.run(append([]string{"-C", tmpd.Join("pkg")}, args...))
+	return code, out.String(), errs.String()
+}
+
+func newModule() (tmpd tdata.TempData, err error) {
+	if tmpd, err = tdata.NewTempData("", "tdata.*"); err == nil {
+		err = tdata.Tree{
+			"go.mod":                      tdata.File("module example\n"),
+			"pkg/pkg.go":                  tdata.File("package pkg\n"),
+			"testdata/file.txt":           tdata.File("file\n"),
+			"testdata/.hidden":            tdata.File("hidden\n"),
+			"testdata/cases/one/input":    tdata.File("one\n"),
+			"testdata/cases/one/.skip":    tdata.File("\n"),
+			"testdata/out.golden":         tdata.File("old\n"),
+			"testdata/out.golden.new":     tdata.File("new\n"),
+			"testdata/cases/two/input":    tdata.File("two\n"),
+			"fixtures/custom.txt":         tdata.File("custom\n"),
+			"testdata/cases/two/output.b": tdata.File("\x00"),
+		}.Apply(tmpd)
+	}
+	return
+}
+
+func TestCLI(t *testing.T) {
+
+	Convey("Usage", t, func() {
+		tmpd, err := newModule()
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		code, _, stderr := runCLI(tmpd, "")
+		So(code, ShouldEqual, 2)
+		So(stderr, ShouldStartWith, "Usage: tdata")
+		code, _, stderr = runCLI(tmpd, "", "bogus")
+		So(code, ShouldEqual, 2)
+		So(stderr, ShouldStartWith, "tdata: unknown command \"bogus\"\nUsage: tdata")
+		code, _, _ = runCLI(tmpd, "", "-bogus")
+		So(code, ShouldEqual, 2)
+		code, _, stderr = runCLI(tmpd, "", "-name", "missing", "ls")
+		So(code, ShouldEqual, 2)
+		So(stderr, ShouldEqual, "tdata: directory not found: missing\n")
+	})
+
+	Convey("ls", t, func() {
+		tmpd, err := newModule()
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		for args, want := range map[string]string{
+			"ls":                    "cases\nfile.txt\nout.golden\nout.golden.new\n",
+			"ls -H":                 "cases\n.hidden\nfile.txt\nout.golden\nout.golden.new\n",
+			"ls -f":                 "file.txt\nout.golden\nout.golden.new\n",
+			"ls -d":                 "cases\n",
+			"ls -r -f cases":        "cases/one/input\ncases/two/input\ncases/two/output.b\n",
+			"ls -r -f -H one":       "",
+			"ls -r -d":              "cases\ncases/one\ncases/two\n",
+			"ls -r -d -H":           "cases\ncases/one\ncases/two\n",
+			"ls -r cases/one":       "cases/one/input\n",
+			"ls -r -H cases":        "cases/one\ncases/two\ncases/one/.skip\ncases/one/input\ncases/two/input\ncases/two/output.b\n",
+			"ls -r -f -H cases/one": "cases/one/.skip\ncases/one/input\n",
+		} {
+			code, stdout, stderr := runCLI(tmpd, "", strings.Fields(args)...)
+			So(stderr, ShouldEqual, "")
+			So(code, ShouldEqual, 0)
+			So(stdout, ShouldEqual, want)
+		}
+
+		code, stdout, _ := runCLI(tmpd, "", "-name", "fixtures", "ls")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "custom.txt\n")
+
+		for _, args := range []string{"ls -f -d", "ls -f -H", "ls a b", "ls -x"} {
+			code, _, _ = runCLI(tmpd, "", strings.Fields(args)...)
+			So(code, ShouldEqual, 2)
+		}
+	})
+
+	Convey("verify", t, func() {
+		tmpd, err := newModule()
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		code, stdout, _ := runCLI(tmpd, "", "verify")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "")
+
+		code, stdout, _ = runCLI(tmpd, "", "verify", "-update")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "updated SHA256SUMS\n")
+		So(tmpd.E("testdata/SHA256SUMS"), ShouldBeTrue)
+
+		So(os.WriteFile(tmpd.Join("testdata", "file.txt"), []byte(tdata.LFSPointerPrefix+"\noid sha256:00\nsize 1\n"), 0644), ShouldBeNil)
+		So(os.WriteFile(tmpd.Join("testdata", "out.golden"), []byte("changed\n"), 0644), ShouldBeNil)
+		code, stdout, _ = runCLI(tmpd, "", "verify")
+		So(code, ShouldEqual, 1)
+		So(stdout, ShouldEqual, ""+
+			"git lfs pointer file: file.txt, run `git lfs pull` to fetch the file contents\n"+
+			"checksum mismatch: out.golden\n",
+		)
+
+		code, _, _ = runCLI(tmpd, "", "verify", "-update", "out.golden")
+		So(code, ShouldEqual, 0)
+		code, stdout, _ = runCLI(tmpd, "", "verify")
+		So(code, ShouldEqual, 1)
+		So(stdout, ShouldNotContainSubstring, "out.golden")
+
+		code, _, _ = runCLI(tmpd, "", "verify", "file.txt")
+		So(code, ShouldEqual, 2)
+		So(os.WriteFile(tmpd.Join("testdata", "SHA256SUMS"), []byte("bad\n"), 0644), ShouldBeNil)
+		code, _, stderr := runCLI(tmpd, "", "verify")
+		So(code, ShouldEqual, 2)
+		So(stderr, ShouldStartWith, "tdata: checksums syntax error")
+	})
+
+	Convey("pack and unpack", t, func() {
+		tmpd, err := newModule()
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		code, stdout, _ := runCLI(tmpd, "", "pack", "cases/one")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "-- .skip --\n\n-- input --\none\n")
+
+		code, _, _ = runCLI(tmpd, "", "pack", "-o", tmpd.Join("one.txtar"), "cases/one")
+		So(code, ShouldEqual, 0)
+		So(tmpd.F("one.txtar"), ShouldEqual, stdout)
+
+		code, stdout, _ = runCLI(tmpd, "", "unpack", "-d", "cases/three", tmpd.Join("one.txtar"))
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "cases/three/.skip\ncases/three/input\n")
+		So(tmpd.F("testdata/cases/three/input"), ShouldEqual, "one\n")
+
+		code, _, stderr := runCLI(tmpd, "-- input --\nchanged\n", "unpack", "-d", "cases/three", "-")
+		So(code, ShouldEqual, 2)
+		So(stderr, ShouldEqual, "tdata: tree conflict: \"cases/three/input\" already exists\n")
+		code, _, _ = runCLI(tmpd, "-- input --\nchanged\n", "unpack", "-f", "-d", "cases/three", "-")
+		So(code, ShouldEqual, 0)
+		So(tmpd.F("testdata/cases/three/input"), ShouldEqual, "changed\n")
+
+		for _, args := range []string{"pack a b", "pack missing", "unpack", "unpack missing.txtar"} {
+			code, _, _ = runCLI(tmpd, "", strings.Fields(args)...)
+			So(code, ShouldEqual, 2)
+		}
+	})
+
+	Convey("lint and accept", t, func() {
+		tmpd, err := newModule()
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		code, stdout, _ := runCLI(tmpd, "", "lint")
+		So(code, ShouldEqual, 1)
+		So(stdout, ShouldEqual, "out.golden.new: pending golden file was not accepted or rejected (pending)\n")
+		code, stdout, _ = runCLI(tmpd, "", "lint", "-skip", tdata.LintPending)
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "")
+		code, _, _ = runCLI(tmpd, "", "lint", "extra")
+		So(code, ShouldEqual, 2)
+		code, stdout, _ = runCLI(tmpd, "", "lint", "-skip", tdata.LintPending, "-max-size", "1", "-max-path", "5")
+		So(code, ShouldEqual, 1)
+		So(stdout, ShouldContainSubstring, "cases/one/input: path is 15 characters long, more than 5 (long-path)\n")
+		So(stdout, ShouldContainSubstring, "file.txt: file is 5 bytes, more than 1 (size)\n")
+
+		code, stdout, _ = runCLI(tmpd, "", "pending")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "out.golden\n")
+		code, stdout, _ = runCLI(tmpd, "", "pending", "-diff", "out.golden.new")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "--- out.golden\n+++ out.golden.new\n@@ -1 +1 @@\n- 1   | old\n+   1 | new\n")
+		code, _, _ = runCLI(tmpd, "", "pending", "-diff", "file.txt")
+		So(code, ShouldEqual, 2)
+
+		code, _, stderr := runCLI(tmpd, "", "accept", "file.txt")
+		So(code, ShouldEqual, 2)
+		So(stderr, ShouldEqual, "tdata: file does not exist: file.txt.new\n")
+		code, stdout, _ = runCLI(tmpd, "", "accept")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "accepted out.golden\n")
+		So(tmpd.F("testdata/out.golden"), ShouldEqual, "new\n")
+
+		code, stdout, _ = runCLI(tmpd, "", "lint")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "")
+	})
+
+	Convey("reject", t, func() {
+		tmpd, err := newModule()
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		code, _, stderr := runCLI(tmpd, "", "reject", "file.txt")
+		So(code, ShouldEqual, 2)
+		So(stderr, ShouldEqual, "tdata: file does not exist: file.txt.new\n")
+		code, stdout, _ := runCLI(tmpd, "", "reject", "out.golden")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "rejected out.golden\n")
+		So(tmpd.F("testdata/out.golden"), ShouldEqual, "old\n")
+		So(tmpd.E("testdata/out.golden.new"), ShouldBeFalse)
+
+		code, stdout, _ = runCLI(tmpd, "", "pending")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "")
+	})
+
+	Convey("fixtures with the pending extension", t, func() {
+		tmpd, err := newModule()
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(os.WriteFile(tmpd.Join("testdata", "data.new"), []byte("data\n"), 0644), ShouldBeNil)
+
+		code, stdout, _ := runCLI(tmpd, "", "pending")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "out.golden\n")
+		code, stdout, _ = runCLI(tmpd, "", "accept")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "accepted out.golden\n")
+		So(os.WriteFile(tmpd.Join("testdata", "out.golden.new"), []byte("newer\n"), 0644), ShouldBeNil)
+		code, stdout, _ = runCLI(tmpd, "", "reject")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "rejected out.golden\n")
+
+		So(tmpd.F("testdata/data.new"), ShouldEqual, "data\n")
+		So(tmpd.E("testdata/data"), ShouldBeFalse)
+	})
+
+	Convey("accept new golden files", t, func() {
+		tmpd, err := newModule()
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(os.WriteFile(tmpd.Join("testdata", "created.golden.new"), []byte("created\n"), 0644), ShouldBeNil)
+		So(os.WriteFile(tmpd.Join("testdata", tdata.PendingIndexFile), []byte("created.golden.new\n"), 0644), ShouldBeNil)
+
+		code, stdout, _ := runCLI(tmpd, "", "pending")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "created.golden\nout.golden\n")
+		code, stdout, _ = runCLI(tmpd, "", "accept")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "accepted created.golden\naccepted out.golden\n")
+		So(tmpd.F("testdata/created.golden"), ShouldEqual, "created\n")
+		So(tmpd.E("testdata/"+tdata.PendingIndexFile), ShouldBeFalse)
+	})
+
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	clPath "github.com/go-corelibs/path"
+)
+
+// CompareOptions configures the behaviour of CompareTrees
+type CompareOptions struct {
+	// Ignore is a list of path.Match patterns, an entry is ignored (along with
+	// anything within it) when a pattern matches either its slash-separated
+	// relative path or its base name
+	Ignore []string
+	// Hidden includes hidden files and directories in the comparison, the
+	// same as the H variants of the TData listing methods. When false, hidden
+	// entries and everything within hidden directories are ignored
+	Hidden bool
+	// IgnoreMode skips the permission checks, useful when comparing against
+	// trees checked out by git which only tracks the executable bit
+	IgnoreMode bool
+	// Normalizers are applied to the contents of both sides of each pair of
+	// text files before they are compared
+	Normalizers []Normalizer
+}
+
+func (o *CompareOptions) ignored(rel string) (ignored bool) {
+	if !o.Hidden {
+		for _, segment := range strings.Split(rel, "/") {
+			if clPath.IsHidden(segment) {
+				return true
+			}
+		}
+	}
+	for check := rel; check != "."; check = path.Dir(check) {
+		for _, pattern := range o.Ignore {
+			if matched, _ := path.Match(pattern, check); matched {
+				return true
+			} else if matched, _ = path.Match(pattern, path.Base(check)); matched {
+				return true
+			}
+		}
+	}
+	return
+}
+
+// TreeReport is the structured result of CompareTrees. All paths are
+// slash-separated, relative to the roots compared and sorted
+type TreeReport struct {
+	// Added lists paths present in got and not in want
+	Added []string
+	// Removed lists paths present in want and not in got
+	Removed []string
+	// TypeChanged lists paths present in both with different EntryTypes
+	TypeChanged []string
+	// ModeChanged lists files and directories with different permissions
+	ModeChanged []string
+	// ContentChanged lists files with different contents and symlinks with
+	// different targets
+	ContentChanged []string
+	// Diffs holds the differences between text files listed in
+	// ContentChanged, keyed by path
+	Diffs map[string]string
+}
+
+// Equal returns true if no differences were found
+func (r *TreeReport) Equal() (equal bool) {
+	return len(r.Added) == 0 &&
+		len(r.Removed) == 0 &&
+		len(r.TypeChanged) == 0 &&
+		len(r.ModeChanged) == 0 &&
+		len(r.ContentChanged) == 0
+}
+
+// String returns a human-readable summary of the differences found
+func (r *TreeReport) String() (report string) {
+	var buf strings.Builder
+	for _, section := range []struct {
+		label string
+		paths []string
+	}{
+		{"added", r.Added},
+		{"removed", r.Removed},
+		{"type changed", r.TypeChanged},
+		{"mode changed", r.ModeChanged},
+		{"content changed", r.ContentChanged},
+	} {
+		for _, p := range section.paths {
+			buf.WriteString(section.label + ": " + p + "\n")
+			if diff, present := r.Diffs[p]; present && section.label == "content changed" {
+				buf.WriteString(diff)
+				if !strings.HasSuffix(diff, "\n") {
+					buf.WriteString("\n")
+				}
+			}
+		}
+	}
+	return buf.String()
+}
+
+type treeEntryInfo struct {
+	abs  string
+	info fs.FileInfo
+}
+
+// CompareTrees compares the entire contents of two TData instances, reporting
+// all paths added to, removed from or otherwise changed in got compared to
+// want. A nil opts is the same as the zero CompareOptions
+func CompareTrees(got, want TData, opts *CompareOptions) (report *TreeReport, err error) {
+	if opts == nil {
+		opts = &CompareOptions{}
+	}
+
+	var gotEntries, wantEntries map[string]treeEntryInfo
+	if gotEntries, err = listTreeEntries(got, opts); err != nil {
+		return
+	} else if wantEntries, err = listTreeEntries(want, opts); err != nil {
+		return
+	}
+
+	report = &TreeReport{Diffs: make(map[string]string)}
+	for rel := range gotEntries {
+		if _, present := wantEntries[rel]; !present {
+			report.Added = append(report.Added, rel)
+		}
+	}
+	for rel, w := range wantEntries {
+		g, present := gotEntries[rel]
+		if !present {
+			report.Removed = append(report.Removed, rel)
+			continue
+		}
+
+		gType, wType := entryTypeOf(g.info.Mode()), entryTypeOf(w.info.Mode())
+		if gType != wType {
+			report.TypeChanged = append(report.TypeChanged, rel)
+			continue
+		}
+
+		if !opts.IgnoreMode && gType != TypeSymlink && g.info.Mode().Perm() != w.info.Mode().Perm() {
+			report.ModeChanged = append(report.ModeChanged, rel)
+		}
+
+		switch gType {
+		case TypeSymlink:
+			gLink, _ := os.Readlink(g.abs)
+			wLink, _ := os.Readlink(w.abs)
+			if gLink != wLink {
+				report.ContentChanged = append(report.ContentChanged, rel)
+			}
+		case TypeFile:
+			var gData, wData []byte
+			if gData, err = os.ReadFile(g.abs); err != nil {
+				return
+			} else if wData, err = os.ReadFile(w.abs); err != nil {
+				return
+			}
+			trackUsage(g.abs, w.abs)
+			if bytes.Equal(gData, wData) {
+				continue
+			} else if isText(gData) && isText(wData) {
+				gText := Normalize(string(gData), opts.Normalizers...)
+				wText := Normalize(string(wData), opts.Normalizers...)
+				if gText != wText {
+					report.ContentChanged = append(report.ContentChanged, rel)
+					report.Diffs[rel] = textDiff(wText, gText)
+				}
+			} else {
+				report.ContentChanged = append(report.ContentChanged, rel)
+			}
+		}
+	}
+
+	sort.Strings(report.Added)
+	sort.Strings(report.Removed)
+	sort.Strings(report.TypeChanged)
+	sort.Strings(report.ModeChanged)
+	sort.Strings(report.ContentChanged)
+	return
+}
+
+func listTreeEntries(td TData, opts *CompareOptions) (entries map[string]treeEntryInfo, err error) {
+	root := td.Path()
+	if !clPath.IsDir(root) {
+		err = fmt.Errorf("%w: %s", ErrNotFound, root)
+		return
+	}
+
+	var found []string
+	if opts.Hidden {
+		found = append(td.LADH("."), td.LAFH(".")...)
+	} else {
+		found = append(td.LAD("."), td.LAF(".")...)
+	}
+
+	entries = make(map[string]treeEntryInfo)
+	for _, abs := range found {
+		var rel string
+		if rel, err = filepath.Rel(root, abs); err != nil {
+			return
+		}
+		rel = filepath.ToSlash(rel)
+		if opts.ignored(rel) {
+			continue
+		}
+		var info fs.FileInfo
+		if info, err = os.Lstat(abs); err != nil {
+			return
+		}
+		entries[rel] = treeEntryInfo{abs: abs, info: info}
+	}
+	return
+}
+
+// isText reports whether the data looks like text: valid UTF-8 without any
+// NUL bytes
+func isText(data []byte) (text bool) {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+}
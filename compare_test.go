@@ -0,0 +1,103 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCompareTrees(t *testing.T) {
+
+	Convey("Against TestData", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"dir":      Dir(),
+			"file.txt": File("test file\n"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		report, err := CompareTrees(tmpd, New(), &CompareOptions{IgnoreMode: true})
+		So(err, ShouldBeNil)
+		So(report.Equal(), ShouldBeTrue)
+		So(report.String(), ShouldEqual, "")
+
+		report, err = CompareTrees(tmpd, New(), &CompareOptions{Hidden: true, IgnoreMode: true})
+		So(err, ShouldBeNil)
+		So(report.Equal(), ShouldBeFalse)
+		So(report.Removed, ShouldEqual, []string{"dir/.gitkeep"})
+	})
+
+	Convey("Differences", t, func() {
+		got, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer got.Destroy()
+		want, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer want.Destroy()
+
+		So(Tree{
+			"same.txt":      File("same"),
+			"added.txt":     File("added"),
+			"type":          Dir(),
+			"mode.sh":       File("mode").WithMode(0755),
+			"text.txt":      File("one\ntwo\n"),
+			"binary.bin":    File("\x00\x01"),
+			"link":          Symlink("same.txt"),
+			".hidden/x.txt": File("x"),
+			"logs/a.log":    File("a"),
+		}.Apply(got), ShouldBeNil)
+		So(Tree{
+			"same.txt":   File("same"),
+			"removed":    Dir(),
+			"type":       File(""),
+			"mode.sh":    File("mode"),
+			"text.txt":   File("one\nthree\n"),
+			"binary.bin": File("\x00\x02"),
+			"link":       Symlink("text.txt"),
+		}.Apply(want), ShouldBeNil)
+
+		report, err := CompareTrees(got, want, &CompareOptions{Ignore: []string{"logs"}})
+		So(err, ShouldBeNil)
+		So(report.Equal(), ShouldBeFalse)
+		So(report.Added, ShouldEqual, []string{"added.txt"})
+		So(report.Removed, ShouldEqual, []string{"removed"})
+		So(report.TypeChanged, ShouldEqual, []string{"type"})
+		So(report.ModeChanged, ShouldEqual, []string{"mode.sh"})
+		So(report.ContentChanged, ShouldEqual, []string{"binary.bin", "link", "text.txt"})
+		So(report.Diffs, ShouldContainKey, "text.txt")
+		So(report.Diffs, ShouldNotContainKey, "binary.bin")
+		So(report.String(), ShouldStartWith, "added: added.txt\nremoved: removed\n")
+
+		report, err = CompareTrees(got, want, &CompareOptions{Hidden: true, Ignore: []string{"*.log", "*.bin"}})
+		So(err, ShouldBeNil)
+		So(report.Added, ShouldEqual, []string{".hidden", ".hidden/x.txt", "added.txt", "logs"})
+		So(report.ContentChanged, ShouldEqual, []string{"link", "text.txt"})
+	})
+
+	Convey("Errors", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		So(tmpd.Destroy(), ShouldBeNil)
+		_, err = CompareTrees(tmpd, New(), nil)
+		So(errors.Is(err, ErrNotFound), ShouldBeTrue)
+		_, err = CompareTrees(New(), tmpd, nil)
+		So(errors.Is(err, ErrNotFound), ShouldBeTrue)
+	})
+
+}
@@ -0,0 +1,342 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultDiffContext is the number of unchanged lines shown around each
+	// change by Diff
+	DefaultDiffContext = 3
+
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiCyan  = "\x1b[36m"
+	ansiReset = "\x1b[0m"
+)
+
+// DiffOptions configures the output of UnifiedDiff
+type DiffOptions struct {
+	// Context is the number of unchanged lines shown around each change,
+	// zero uses DefaultDiffContext and negative values show no context
+	Context int
+	// Color wraps the header, removed and added lines in ANSI colors
+	Color bool
+	// LabelA names the first argument in the header, "a" when empty
+	LabelA string
+	// LabelB names the second argument in the header, "b" when empty
+	LabelB string
+}
+
+// Diff is a convenience wrapper around UnifiedDiff with the default options
+func Diff(a, b string) (diff string) {
+	return UnifiedDiff(a, b, DiffOptions{})
+}
+
+// textDiff is the UnifiedDiff used by all the comparison helpers
+func textDiff(want, got string) (diff string) {
+	return UnifiedDiff(want, got, DiffOptions{LabelA: "want", LabelB: "got"})
+}
+
+// UnifiedDiff returns the line-oriented differences between a and b in the
+// unified diff format, with an additional gutter of line numbers for each
+// side. An empty string is returned when a and b are equal
+//
+// Example:
+//
+//	--- a
+//	+++ b
+//	@@ -1,3 +1,3 @@
+//	  1 1 | one
+//	- 2   | two
+//	+   2 | three
+//	  3 3 | four
+func UnifiedDiff(a, b string, opts DiffOptions) (diff string) {
+	if a == b {
+		return
+	}
+
+	if opts.Context == 0 {
+		opts.Context = DefaultDiffContext
+	} else if opts.Context < 0 {
+		opts.Context = 0
+	}
+	if opts.LabelA == "" {
+		opts.LabelA = "a"
+	}
+	if opts.LabelB == "" {
+		opts.LabelB = "b"
+	}
+
+	aLines, bLines := splitLines(a), splitLines(b)
+	edits := myers(aLines, bLines)
+
+	width := len(strconv.Itoa(max(len(aLines), len(bLines))))
+	colorize := func(color, text string) string {
+		if opts.Color {
+			return color + text + ansiReset
+		}
+		return text
+	}
+	number := func(n int) string {
+		if n < 0 {
+			return strings.Repeat(" ", width)
+		}
+		return fmt.Sprintf("%*d", width, n+1)
+	}
+
+	var buf strings.Builder
+	buf.WriteString(colorize(ansiRed, "--- "+opts.LabelA) + "\n")
+	buf.WriteString(colorize(ansiGreen, "+++ "+opts.LabelB) + "\n")
+
+	for _, h := range diffHunks(edits, opts.Context) {
+		var aStart, aCount, bStart, bCount int
+		aStart, bStart = -1, -1
+		for _, e := range h {
+			if e.a >= 0 {
+				if aStart < 0 {
+					aStart = e.a
+				}
+				aCount++
+			}
+			if e.b >= 0 {
+				if bStart < 0 {
+					bStart = e.b
+				}
+				bCount++
+			}
+		}
+		buf.WriteString(colorize(ansiCyan, fmt.Sprintf("@@ -%s +%s @@", hunkRange(aStart, aCount, h[0].a0), hunkRange(bStart, bCount, h[0].b0))) + "\n")
+
+		for _, e := range h {
+			var line, marker, color string
+			switch e.op {
+			case ' ':
+				line, marker = aLines[e.a], " "
+			case '-':
+				line, marker, color = aLines[e.a], "-", ansiRed
+			case '+':
+				line, marker, color = bLines[e.b], "+", ansiGreen
+			}
+			text := marker + " " + number(e.a) + " " + number(e.b) + " | " + strings.TrimSuffix(line, "\n")
+			if color != "" {
+				text = colorize(color, text)
+			}
+			buf.WriteString(text + "\n")
+			if !strings.HasSuffix(line, "\n") {
+				buf.WriteString(`\ No newline at end of file` + "\n")
+			}
+		}
+	}
+	return buf.String()
+}
+
+// diffEdit is a single line of an edit script, a and b are the zero-based
+// line indexes within each side, or -1 when not applicable to the op. a0 and
+// b0 track the position within each side, used for empty hunk ranges
+type diffEdit struct {
+	op     byte
+	a, b   int
+	a0, b0 int
+}
+
+func hunkRange(start, count, pos int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", pos)
+	} else if count == 1 {
+		return strconv.Itoa(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits the text into lines, each retaining the trailing newline
+// except for the last line if the text does not end with one
+func splitLines(text string) (lines []string) {
+	if text == "" {
+		return
+	}
+	lines = strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return
+}
+
+// maxDiffCost caps the number of differing lines searched for the shortest
+// edit script, beyond which the differences are reported as replacing all of
+// the lines between the common prefix and suffix, keeping the time and memory
+// used by huge mismatches bounded
+const maxDiffCost = 1024
+
+// myers returns the shortest edit script transforming a into b, using the
+// greedy algorithm described in "An O(ND) Difference Algorithm and Its
+// Variations" by Eugene W. Myers
+func myers(a, b []string) (edits []diffEdit) {
+	// the common prefix and suffix are never part of the shortest edit script
+	var prefix, suffix int
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	for idx := range prefix {
+		edits = append(edits, diffEdit{op: ' ', a: idx, b: idx})
+	}
+	aMid, bMid := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	middle, ok := myersSearch(aMid, bMid, maxDiffCost)
+	if !ok {
+		middle = replaceEdits(aMid, bMid)
+	}
+	for _, e := range middle {
+		if e.a >= 0 {
+			e.a += prefix
+		}
+		if e.b >= 0 {
+			e.b += prefix
+		}
+		edits = append(edits, e)
+	}
+	for idx := range suffix {
+		edits = append(edits, diffEdit{op: ' ', a: len(a) - suffix + idx, b: len(b) - suffix + idx})
+	}
+
+	// track the positions for each edit
+	var ai, bi int
+	for idx := range edits {
+		edits[idx].a0, edits[idx].b0 = ai, bi
+		if edits[idx].a >= 0 {
+			ai++
+		}
+		if edits[idx].b >= 0 {
+			bi++
+		}
+	}
+	return
+}
+
+// myersSearch returns the shortest edit script transforming a into b, or
+// false when it has more than limit insertions and deletions
+func myersSearch(a, b []string, limit int) (edits []diffEdit, ok bool) {
+	n, m := len(a), len(b)
+	limit = min(limit, n+m)
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	// trace holds the v of each step for k from -d to d, which is all that
+	// the backtracking reads
+	var trace [][]int
+
+	for d := 0; d <= limit && !ok; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				ok = true
+				break
+			}
+		}
+	}
+	if !ok {
+		return
+	}
+
+	// backtrack through the trace, building the edits in reverse
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		var prevX, prevY int
+		if d > 0 {
+			vd := trace[d]
+			k := x - y
+			prevK := k - 1
+			if k == -d || (k != d && vd[d+k-1] < vd[d+k+1]) {
+				prevK = k + 1
+			}
+			prevX = vd[d+prevK]
+			prevY = prevX - prevK
+		}
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			edits = append(edits, diffEdit{op: ' ', a: x, b: y})
+		}
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, diffEdit{op: '+', a: -1, b: prevY})
+			} else {
+				edits = append(edits, diffEdit{op: '-', a: prevX, b: -1})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return
+}
+
+// replaceEdits returns the edit script deleting all of a and then inserting
+// all of b
+func replaceEdits(a, b []string) (edits []diffEdit) {
+	for idx := range a {
+		edits = append(edits, diffEdit{op: '-', a: idx, b: -1})
+	}
+	for idx := range b {
+		edits = append(edits, diffEdit{op: '+', a: -1, b: idx})
+	}
+	return
+}
+
+// diffHunks groups the edits into hunks of changes, each surrounded by up to
+// context unchanged lines
+func diffHunks(edits []diffEdit, context int) (hunks [][]diffEdit) {
+	var current []diffEdit
+	lastChange := -1
+	for idx, e := range edits {
+		if e.op == ' ' {
+			continue
+		}
+		start := max(idx-context, 0)
+		if current != nil && start > lastChange+context+1 {
+			// too far from the previous change, close the current hunk
+			hunks = append(hunks, append(current, edits[lastChange+1:min(lastChange+1+context, len(edits))]...))
+			current = nil
+		}
+		if current == nil {
+			current = append(current, edits[start:idx]...)
+		} else {
+			current = append(current, edits[lastChange+1:idx]...)
+		}
+		current = append(current, e)
+		lastChange = idx
+	}
+	if current != nil {
+		hunks = append(hunks, append(current, edits[lastChange+1:min(lastChange+1+context, len(edits))]...))
+	}
+	return
+}
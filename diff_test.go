@@ -0,0 +1,158 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDiff(t *testing.T) {
+
+	Convey("Equal", t, func() {
+		So(Diff("", ""), ShouldEqual, "")
+		So(Diff("one\ntwo\n", "one\ntwo\n"), ShouldEqual, "")
+	})
+
+	Convey("Simple", t, func() {
+		So(Diff("one\ntwo\nfour\n", "one\nthree\nfour\n"), ShouldEqual, ""+
+			"--- a\n"+
+			"+++ b\n"+
+			"@@ -1,3 +1,3 @@\n"+
+			"  1 1 | one\n"+
+			"- 2   | two\n"+
+			"+   2 | three\n"+
+			"  3 3 | four\n",
+		)
+		So(Diff("", "new\n"), ShouldEqual, ""+
+			"--- a\n"+
+			"+++ b\n"+
+			"@@ -0,0 +1 @@\n"+
+			"+   1 | new\n",
+		)
+		So(Diff("old\n", ""), ShouldEqual, ""+
+			"--- a\n"+
+			"+++ b\n"+
+			"@@ -1 +0,0 @@\n"+
+			"- 1   | old\n",
+		)
+		So(Diff("same", "same\n"), ShouldEqual, ""+
+			"--- a\n"+
+			"+++ b\n"+
+			"@@ -1 +1 @@\n"+
+			"- 1   | same\n"+
+			"\\ No newline at end of file\n"+
+			"+   1 | same\n",
+		)
+	})
+
+	Convey("Hunks", t, func() {
+		var a, b []string
+		for _, word := range strings.Fields("a b c d e f g h i j k l m n o p q r s t") {
+			a = append(a, word)
+			b = append(b, word)
+		}
+		b[1] = "B"
+		b[17] = "R"
+		b = append(b[:9], b[10:]...) // remove "j"
+		diff := UnifiedDiff(strings.Join(a, "\n")+"\n", strings.Join(b, "\n")+"\n", DiffOptions{
+			Context: 2,
+			LabelA:  "want",
+			LabelB:  "got",
+		})
+		So(diff, ShouldEqual, ""+
+			"--- want\n"+
+			"+++ got\n"+
+			"@@ -1,4 +1,4 @@\n"+
+			"   1  1 | a\n"+
+			"-  2    | b\n"+
+			"+     2 | B\n"+
+			"   3  3 | c\n"+
+			"   4  4 | d\n"+
+			"@@ -8,5 +8,4 @@\n"+
+			"   8  8 | h\n"+
+			"   9  9 | i\n"+
+			"- 10    | j\n"+
+			"  11 10 | k\n"+
+			"  12 11 | l\n"+
+			"@@ -16,5 +15,5 @@\n"+
+			"  16 15 | p\n"+
+			"  17 16 | q\n"+
+			"- 18    | r\n"+
+			"+    17 | R\n"+
+			"  19 18 | s\n"+
+			"  20 19 | t\n",
+		)
+
+		diff = UnifiedDiff("a\nb\nc\n", "a\nx\nc\n", DiffOptions{Context: -1})
+		So(diff, ShouldEqual, ""+
+			"--- a\n"+
+			"+++ b\n"+
+			"@@ -2 +2 @@\n"+
+			"- 2   | b\n"+
+			"+   2 | x\n",
+		)
+	})
+
+	Convey("Color", t, func() {
+		diff := UnifiedDiff("a\n", "b\n", DiffOptions{Color: true})
+		So(diff, ShouldEqual, ""+
+			ansiRed+"--- a"+ansiReset+"\n"+
+			ansiGreen+"+++ b"+ansiReset+"\n"+
+			ansiCyan+"@@ -1 +1 @@"+ansiReset+"\n"+
+			ansiRed+"- 1   | a"+ansiReset+"\n"+
+			ansiGreen+"+   1 | b"+ansiReset+"\n",
+		)
+	})
+
+	Convey("Large", t, func() {
+		var a, b, c strings.Builder
+		for idx := range 6000 {
+			fmt.Fprintf(&a, "a %d\n", idx)
+			fmt.Fprintf(&b, "b %d\n", idx)
+			if idx%100 == 0 {
+				fmt.Fprintf(&c, "c %d\n", idx)
+			} else {
+				fmt.Fprintf(&c, "a %d\n", idx)
+			}
+		}
+
+		// no lines in common is beyond the maxDiffCost, a single replacement
+		start := time.Now()
+		diff := Diff(a.String(), b.String())
+		So(time.Since(start), ShouldBeLessThan, 2*time.Second)
+		So(strings.Count(diff, "@@ "), ShouldEqual, 1)
+		So(diff, ShouldContainSubstring, "@@ -1,6000 +1,6000 @@\n")
+		So(strings.Count(diff, "\n- "), ShouldEqual, 6000)
+		So(strings.Count(diff, "\n+ "), ShouldEqual, 6000)
+
+		// few changes within a large input are still the shortest edit script
+		start = time.Now()
+		diff = Diff(a.String(), c.String())
+		So(time.Since(start), ShouldBeLessThan, 2*time.Second)
+		So(strings.Count(diff, "\n- "), ShouldEqual, 60)
+		So(strings.Count(diff, "\n+ "), ShouldEqual, 60)
+
+		edits := myers(splitLines("same\none\ntwo\nend\n"), splitLines("same\nuno\ndos\nend\n"))
+		So(edits, ShouldHaveLength, 6)
+		So(edits[1], ShouldResemble, diffEdit{op: '-', a: 1, b: -1, a0: 1, b0: 1})
+		So(edits[5], ShouldResemble, diffEdit{op: ' ', a: 3, b: 3, a0: 3, b0: 3})
+	})
+
+}
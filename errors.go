@@ -19,6 +19,23 @@ import (
 )
 
 var (
-	ErrNotFound      = errors.New("directory not found")
-	ErrRuntimeCaller = errors.New("runtime.Caller not ok")
+	ErrNotFound          = errors.New("directory not found")
+	ErrRuntimeCaller     = errors.New("runtime.Caller not ok")
+	ErrTreePath          = errors.New("invalid tree path")
+	ErrTreeConflict      = errors.New("tree conflict")
+	ErrTreeMismatch      = errors.New("tree mismatch")
+	ErrManifestSyntax    = errors.New("manifest syntax error")
+	ErrSnapshotSyntax    = errors.New("snapshot syntax error")
+	ErrHTTPFixtureSyntax = errors.New("http fixture syntax error")
+	ErrCassetteMiss      = errors.New("cassette interaction not found")
+	ErrCasesFormat       = errors.New("unsupported cases format")
+	ErrCaseInvalid       = errors.New("invalid case")
+	ErrFuzzSyntax        = errors.New("fuzz corpus syntax error")
+	ErrFuzzValue         = errors.New("unsupported fuzz value")
+	ErrChecksumsSyntax   = errors.New("checksums syntax error")
+	ErrChecksumMismatch  = errors.New("checksum mismatch")
+	ErrLFSPointer        = errors.New("git lfs pointer file")
+	ErrTxtarSyntax       = errors.New("txtar syntax error")
+	ErrUpdateScope       = errors.New("invalid update scope")
+	ErrGoldenConflict    = errors.New("golden update conflict")
 )
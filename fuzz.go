@@ -0,0 +1,328 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const (
+	// FuzzHeader is the first line of every "go test fuzz v1" corpus file
+	FuzzHeader = "go test fuzz v1"
+	// FuzzCorpusDir is the directory, within a module's testdata, where the
+	// go command stores the corpus entries of each fuzz test
+	FuzzCorpusDir = "fuzz"
+)
+
+// SeedFuzz adds the contents of every file within the dirname of td, in
+// lexical order, to the seed corpus of f as a single []byte argument. Any
+// files within the FuzzCorpusDir of td are skipped as the go command loads
+// those itself
+func SeedFuzz(f *testing.F, td TData, dirname string) {
+	f.Helper()
+	corpus := td.Join(FuzzCorpusDir) + string(filepath.Separator)
+	var seeded int
+	for _, path := range td.LAF(dirname) {
+		if strings.HasPrefix(path, corpus) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatalf("error reading fuzz seed %q: %v", path, err)
+			return
+		}
+		trackUsage(path)
+		f.Add(data)
+		seeded++
+	}
+	if seeded == 0 {
+		f.Errorf("no fuzz seed files found in %q", dirname)
+	}
+}
+
+// MarshalFuzzCorpus encodes the values in the "go test fuzz v1" format. The
+// supported types are the same as the go command's: []byte, string, bool,
+// byte, rune and the other integer and floating point types
+func MarshalFuzzCorpus(values ...any) (data []byte, err error) {
+	var buf bytes.Buffer
+	buf.WriteString(FuzzHeader + "\n")
+	for _, value := range values {
+		switch v := value.(type) {
+		case []byte:
+			fmt.Fprintf(&buf, "[]byte(%q)\n", v)
+		case string:
+			fmt.Fprintf(&buf, "string(%q)\n", v)
+		case bool:
+			fmt.Fprintf(&buf, "bool(%v)\n", v)
+		case byte:
+			fmt.Fprintf(&buf, "byte(%q)\n", v)
+		case rune:
+			if utf8.ValidRune(v) {
+				fmt.Fprintf(&buf, "rune(%q)\n", v)
+			} else {
+				fmt.Fprintf(&buf, "int32(%d)\n", v)
+			}
+		case int, int8, int16, int64, uint, uint16, uint32, uint64:
+			fmt.Fprintf(&buf, "%T(%d)\n", v, v)
+		case float32:
+			if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+				fmt.Fprintf(&buf, "math.Float32frombits(0x%x)\n", math.Float32bits(v))
+			} else {
+				fmt.Fprintf(&buf, "float32(%s)\n", strconv.FormatFloat(float64(v), 'g', -1, 32))
+			}
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				fmt.Fprintf(&buf, "math.Float64frombits(0x%x)\n", math.Float64bits(v))
+			} else {
+				fmt.Fprintf(&buf, "float64(%s)\n", strconv.FormatFloat(v, 'g', -1, 64))
+			}
+		default:
+			err = fmt.Errorf("%w: %T", ErrFuzzValue, value)
+			return
+		}
+	}
+	data = buf.Bytes()
+	return
+}
+
+// ParseFuzzCorpus decodes the values of a "go test fuzz v1" corpus file
+func ParseFuzzCorpus(data []byte) (values []any, err error) {
+	lines := strings.Split(string(data), "\n")
+	if strings.TrimSpace(lines[0]) != FuzzHeader {
+		err = fmt.Errorf("%w: missing %q header", ErrFuzzSyntax, FuzzHeader)
+		return
+	}
+	for idx, line := range lines[1:] {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		var value any
+		if value, err = parseFuzzValue(line); err != nil {
+			err = fmt.Errorf("%w: line %d: %v", ErrFuzzSyntax, idx+2, err)
+			return
+		}
+		values = append(values, value)
+	}
+	return
+}
+
+// fuzzIntBits is the bit size of each integer type, for range checking
+var fuzzIntBits = map[string]int{
+	"int": strconv.IntSize, "int8": 8, "int16": 16, "int32": 32, "rune": 32, "int64": 64,
+	"uint": strconv.IntSize, "uint8": 8, "byte": 8, "uint16": 16, "uint32": 32, "uint64": 64,
+}
+
+func parseFuzzValue(line string) (value any, err error) {
+	var expr ast.Expr
+	if expr, err = parser.ParseExpr(line); err != nil {
+		return
+	}
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return nil, fmt.Errorf("expected a type conversion: %s", line)
+	}
+
+	var typeName string
+	switch fn := call.Fun.(type) {
+	case *ast.Ident:
+		typeName = fn.Name
+	case *ast.ArrayType:
+		if elt, ok := fn.Elt.(*ast.Ident); ok && fn.Len == nil && elt.Name == "byte" {
+			typeName = "[]byte"
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := fn.X.(*ast.Ident); ok && pkg.Name == "math" {
+			typeName = "math." + fn.Sel.Name
+		}
+	}
+
+	if typeName == "bool" {
+		if ident, ok := call.Args[0].(*ast.Ident); ok && (ident.Name == "true" || ident.Name == "false") {
+			return ident.Name == "true", nil
+		}
+		return nil, fmt.Errorf("invalid bool: %s", line)
+	}
+
+	// literals, with an optional leading minus sign
+	arg := call.Args[0]
+	negative := false
+	if unary, ok := arg.(*ast.UnaryExpr); ok && unary.Op == token.SUB {
+		arg, negative = unary.X, true
+	}
+	lit, ok := arg.(*ast.BasicLit)
+	if !ok {
+		return nil, fmt.Errorf("expected a literal value: %s", line)
+	}
+	text := lit.Value
+	if negative {
+		text = "-" + text
+	}
+
+	switch typeName {
+	case "[]byte", "string":
+		if lit.Kind != token.STRING || negative {
+			return nil, fmt.Errorf("invalid %s: %s", typeName, line)
+		}
+		var s string
+		if s, err = strconv.Unquote(lit.Value); err == nil && typeName == "[]byte" {
+			return []byte(s), nil
+		}
+		return s, err
+	case "byte", "rune", "int32", "uint8":
+		if lit.Kind == token.CHAR && !negative {
+			var s string
+			if s, err = strconv.Unquote(lit.Value); err != nil {
+				return
+			}
+			// a single byte escape, such as '\xff', unquotes to the raw byte
+			r, _ := utf8.DecodeRuneInString(s)
+			if len(s) == 1 {
+				r = rune(s[0])
+			}
+			if typeName == "byte" || typeName == "uint8" {
+				if r > math.MaxUint8 {
+					return nil, fmt.Errorf("byte out of range: %s", line)
+				}
+				return byte(r), nil
+			}
+			return r, nil
+		}
+	case "math.Float32frombits", "math.Float64frombits":
+		var bits uint64
+		if bits, err = strconv.ParseUint(text, 0, 64); err != nil {
+			return
+		}
+		if typeName == "math.Float32frombits" {
+			return math.Float32frombits(uint32(bits)), nil
+		}
+		return math.Float64frombits(bits), nil
+	case "float32", "float64":
+		var f float64
+		bitSize := 64
+		if typeName == "float32" {
+			bitSize = 32
+		}
+		if f, err = strconv.ParseFloat(text, bitSize); err != nil {
+			return
+		}
+		if bitSize == 32 {
+			return float32(f), nil
+		}
+		return f, nil
+	}
+
+	if lit.Kind != token.INT {
+		return nil, fmt.Errorf("unsupported value: %s", line)
+	}
+	switch typeName {
+	case "int", "int8", "int16", "int32", "rune", "int64":
+		var i int64
+		if i, err = strconv.ParseInt(text, 0, fuzzIntBits[typeName]); err != nil {
+			return
+		}
+		switch typeName {
+		case "int":
+			value = int(i)
+		case "int8":
+			value = int8(i)
+		case "int16":
+			value = int16(i)
+		case "int32", "rune":
+			value = int32(i)
+		default:
+			value = i
+		}
+	case "uint", "uint8", "byte", "uint16", "uint32", "uint64":
+		var u uint64
+		if u, err = strconv.ParseUint(text, 0, fuzzIntBits[typeName]); err != nil {
+			return
+		}
+		switch typeName {
+		case "uint":
+			value = uint(u)
+		case "uint8", "byte":
+			value = uint8(u)
+		case "uint16":
+			value = uint16(u)
+		case "uint32":
+			value = uint32(u)
+		default:
+			value = u
+		}
+	}
+	if value != nil {
+		return
+	}
+	return nil, fmt.Errorf("unsupported type %q: %s", typeName, line)
+}
+
+// ReadFuzzCorpus reads and parses the named corpus entry of the fuzzName
+// test, within the FuzzCorpusDir of td
+func ReadFuzzCorpus(td TData, fuzzName, entry string) (values []any, err error) {
+	var data []byte
+	if data, err = os.ReadFile(td.Join(FuzzCorpusDir, fuzzName, entry)); err == nil {
+		values, err = ParseFuzzCorpus(data)
+	}
+	return
+}
+
+// WriteFuzzCorpus adds a corpus entry for the fuzzName test, within the
+// FuzzCorpusDir of td, named after the hash of its contents as the go
+// command does
+func WriteFuzzCorpus(td TData, fuzzName string, values ...any) (entry string, err error) {
+	var data []byte
+	if data, err = MarshalFuzzCorpus(values...); err != nil {
+		return
+	}
+	entry = fmt.Sprintf("%x", sha256.Sum256(data))[:16]
+	err = writeFileAtomic(td.Join(FuzzCorpusDir, fuzzName, entry), data, 0644)
+	return
+}
+
+// FuzzToFixture converts the named corpus entry of the fuzzName test into a
+// regular fixture file within td, containing the raw []byte or string value
+// of the entry, so that a failure found by fuzzing can become a named
+// regression test. The entry must contain a single []byte or string value
+func FuzzToFixture(td TData, fuzzName, entry, fixture string) (err error) {
+	var values []any
+	if values, err = ReadFuzzCorpus(td, fuzzName, entry); err != nil {
+		return
+	}
+	if len(values) != 1 {
+		return fmt.Errorf("%w: %s/%s has %d values, expected one", ErrFuzzValue, fuzzName, entry, len(values))
+	}
+	var data []byte
+	switch value := values[0].(type) {
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return fmt.Errorf("%w: %s/%s has a %T value, expected []byte or string", ErrFuzzValue, fuzzName, entry, value)
+	}
+	return writeFileAtomic(td.Join(fixture), data, 0644)
+}
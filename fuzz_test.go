@@ -0,0 +1,161 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"errors"
+	"math"
+	"os"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFuzzCorpus(t *testing.T) {
+
+	Convey("Marshal and Parse", t, func() {
+		values := []any{
+			[]byte("\x00binary\n"), "text \"quoted\"", true, byte('b'), rune('é'), int32(-1),
+			int(-42), int8(-8), int16(16), int64(math.MinInt64), uint(7), uint16(65535), uint32(32), uint64(math.MaxUint64),
+			float32(1.5), 0.1, math.Inf(-1),
+		}
+		data, err := MarshalFuzzCorpus(values...)
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, ""+
+			"go test fuzz v1\n"+
+			"[]byte(\"\\x00binary\\n\")\n"+
+			"string(\"text \\\"quoted\\\"\")\n"+
+			"bool(true)\n"+
+			"byte('b')\n"+
+			"rune('é')\n"+
+			"int32(-1)\n"+
+			"int(-42)\n"+
+			"int8(-8)\n"+
+			"int16(16)\n"+
+			"int64(-9223372036854775808)\n"+
+			"uint(7)\n"+
+			"uint16(65535)\n"+
+			"uint32(32)\n"+
+			"uint64(18446744073709551615)\n"+
+			"float32(1.5)\n"+
+			"float64(0.1)\n"+
+			"math.Float64frombits(0xfff0000000000000)\n",
+		)
+		parsed, err := ParseFuzzCorpus(data)
+		So(err, ShouldBeNil)
+		So(parsed, ShouldResemble, values)
+
+		// quotes, backslashes and non-ASCII runes round-trip
+		values = []any{
+			byte('\''), byte('"'), byte('\\'), byte(0), byte(0x7f), byte(0xff),
+			rune('\''), rune('"'), rune('\\'), rune('\n'), rune('ÿ'), rune('世'), rune('😀'), rune(0xfffd),
+			"'\"\\世", []byte("'\"\\\xff"),
+		}
+		data, err = MarshalFuzzCorpus(values...)
+		So(err, ShouldBeNil)
+		parsed, err = ParseFuzzCorpus(data)
+		So(err, ShouldBeNil)
+		So(parsed, ShouldResemble, values)
+		parsed, err = ParseFuzzCorpus([]byte("go test fuzz v1\nbyte('\\xff')\nrune('\\u00e9')\n"))
+		So(err, ShouldBeNil)
+		So(parsed, ShouldResemble, []any{byte(0xff), rune('é')})
+
+		nan, err := MarshalFuzzCorpus(float32(math.NaN()))
+		So(err, ShouldBeNil)
+		parsed, err = ParseFuzzCorpus(nan)
+		So(err, ShouldBeNil)
+		So(math.IsNaN(float64(parsed[0].(float32))), ShouldBeTrue)
+
+		// as written by the go command
+		parsed, err = ParseFuzzCorpus([]byte("go test fuzz v1\nstring(\"0\")\nint(0x10)\nfloat64(3)\n\n"))
+		So(err, ShouldBeNil)
+		So(parsed, ShouldResemble, []any{"0", 16, float64(3)})
+
+		_, err = MarshalFuzzCorpus(struct{}{})
+		So(errors.Is(err, ErrFuzzValue), ShouldBeTrue)
+
+		for _, data := range []string{
+			"",
+			"go test fuzz v2\n",
+			"go test fuzz v1\nstring(1)\n",
+			"go test fuzz v1\nint8(128)\n",
+			"go test fuzz v1\nbool(1)\n",
+			"go test fuzz v1\ncomplex128(1)\n",
+			"go test fuzz v1\nfmt.Println(\"x\")\n",
+			"go test fuzz v1\n[]byte(x)\n",
+			"go test fuzz v1\nnot go\n",
+		} {
+			_, err = ParseFuzzCorpus([]byte(data))
+			So(errors.Is(err, ErrFuzzSyntax), ShouldBeTrue)
+		}
+	})
+
+	Convey("Corpus Files", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		entry, err := WriteFuzzCorpus(tmpd, "FuzzParse", []byte("crash\x00"))
+		So(err, ShouldBeNil)
+		So(entry, ShouldHaveLength, 16)
+		So(tmpd.E("fuzz/FuzzParse/"+entry), ShouldBeTrue)
+
+		values, err := ReadFuzzCorpus(tmpd, "FuzzParse", entry)
+		So(err, ShouldBeNil)
+		So(values, ShouldResemble, []any{[]byte("crash\x00")})
+
+		So(FuzzToFixture(tmpd, "FuzzParse", entry, "regressions/crash.bin"), ShouldBeNil)
+		So(tmpd.F("regressions/crash.bin"), ShouldEqual, "crash\x00")
+
+		pair, err := WriteFuzzCorpus(tmpd, "FuzzParse", "a", 1)
+		So(err, ShouldBeNil)
+		So(errors.Is(FuzzToFixture(tmpd, "FuzzParse", pair, "pair.bin"), ErrFuzzValue), ShouldBeTrue)
+		number, err := WriteFuzzCorpus(tmpd, "FuzzParse", 1)
+		So(err, ShouldBeNil)
+		So(errors.Is(FuzzToFixture(tmpd, "FuzzParse", number, "number.bin"), ErrFuzzValue), ShouldBeTrue)
+		So(FuzzToFixture(tmpd, "FuzzParse", "missing", "missing.bin"), ShouldNotBeNil)
+	})
+
+}
+
+func FuzzSeedFuzz(f *testing.F) {
+	tmpd, err := NewTempData("", "tdata.*")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Cleanup(func() { _ = tmpd.Destroy() })
+	_ = os.MkdirAll(tmpd.Join("seeds", "nested"), 0755)
+	_ = os.WriteFile(tmpd.Join("seeds", "one.txt"), []byte("one"), 0644)
+	_ = os.WriteFile(tmpd.Join("seeds", "nested", "two.txt"), []byte("two"), 0644)
+	if _, err = WriteFuzzCorpus(tmpd, "FuzzSeedFuzz", []byte("skipped")); err != nil {
+		f.Fatal(err)
+	}
+
+	SeedFuzz(f, tmpd, "")
+
+	var lock sync.Mutex
+	seen := make(map[string]bool)
+	f.Cleanup(func() {
+		if !seen["one"] || !seen["two"] || seen["skipped"] {
+			f.Errorf("unexpected seed corpus: %v", seen)
+		}
+	})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		lock.Lock()
+		defer lock.Unlock()
+		seen[string(data)] = true
+	})
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+)
+
+// GoldenOption configures a single call to one of the golden helpers
+type GoldenOption func(cfg *goldenConfig)
+
+// WithNormalizers applies the given normalizers to both the golden file
+// contents and the actual output before they are compared, and to the output
+// written when Updating
+func WithNormalizers(normalizers ...Normalizer) GoldenOption {
+	return func(cfg *goldenConfig) {
+		cfg.normalizers = append(cfg.normalizers, normalizers...)
+	}
+}
+
+type goldenConfig struct {
+	normalizers []Normalizer
+}
+
+func newGoldenConfig(options []GoldenOption) (cfg *goldenConfig) {
+	cfg = &goldenConfig{}
+	for _, option := range options {
+		option(cfg)
+	}
+	return
+}
+
+func (cfg *goldenConfig) normalize(text string) (normalized string) {
+	return Normalize(text, cfg.normalizers...)
+}
+
+// Golden compares got with the contents of the named golden file within td,
+// failing the test if they differ. When Updating, the golden file is written
+// with got instead. The most specific variant of the golden file which exists
+// for the CurrentPlatform is used in place of the name, see Platform.Variants
+func Golden(t testing.TB, td TData, name string, got string, options ...GoldenOption) {
+	t.Helper()
+	cfg := newGoldenConfig(options)
+	got = cfg.normalize(got)
+	golden(t, td, name, []byte(got), func(want []byte) (problem string) {
+		if expected := cfg.normalize(string(want)); expected != got {
+			problem = textDiff(expected, got)
+		}
+		return
+	})
+}
+
+// golden is the common implementation of all golden file helpers, the most
+// specific variant of the named golden file is used and when the golden file
+// is not within the scope of an update, the compare func is given the golden
+// file contents and returns a non-empty description of the problem if got
+// does not match. When Reviewing, missing and mismatched golden files have
+// got written as their pending replacement and any stale replacement of a
+// matching golden file is removed
+func golden(t testing.TB, td TData, name string, got []byte, compare func(want []byte) (problem string)) {
+	t.Helper()
+	noteGuardTest(t)
+	checkChecksums(t, td)
+
+	resolved := ResolveGolden(td, name, goldenPlatform)
+	updating := updatingGolden(t, name, resolved)
+	name = resolved
+	if updating {
+		if written, err := updateGolden(t, td, name, got); err != nil {
+			t.Fatalf("error updating golden file %q: %v", name, err)
+		} else if written {
+			t.Logf("updated golden file: %s", name)
+		}
+		return
+	}
+
+	want, err := readGolden(td, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("golden file %q not found, run with -%s to create it", name, UpdateFlag)
+			if Reviewing() {
+				reviewPending(t, td, name, got)
+			}
+		} else {
+			t.Errorf("error reading golden file %q: %v", name, err)
+		}
+		return
+	}
+
+	if problem := compare(want); problem != "" {
+		t.Errorf("golden file %q mismatch:\n%s", name, problem)
+		if Reviewing() {
+			reviewPending(t, td, name, got)
+		}
+	} else if Reviewing() {
+		discardPending(td, name)
+	}
+}
+
+// CompressedExtension is the golden file extension which indicates the file
+// is stored gzip compressed. All golden helpers transparently decompress
+// these files when reading and compress them when Updating
+const CompressedExtension = ".gz"
+
+func readGolden(td TData, name string) (data []byte, err error) {
+	if data, err = os.ReadFile(td.Join(name)); err == nil {
+		data, err = decodeGolden(name, data)
+	}
+	return
+}
+
+func writeGolden(td TData, name string, data []byte) (err error) {
+	return writeGoldenAs(td, name, name, data)
+}
+
+// writeGoldenAs writes the data, encoded for the golden file name given, to
+// the filename within td
+func writeGoldenAs(td TData, name, filename string, data []byte) (err error) {
+	if data, err = encodeGolden(name, data); err != nil {
+		return
+	}
+	return writeFileAtomic(td.Join(filename), data, 0644)
+}
+
+// decodeGolden decompresses the data of the golden file name given, if it
+// has the CompressedExtension
+func decodeGolden(name string, data []byte) (decoded []byte, err error) {
+	if !strings.HasSuffix(name, CompressedExtension) {
+		return data, nil
+	}
+	var gz *gzip.Reader
+	if gz, err = gzip.NewReader(bytes.NewReader(data)); err != nil {
+		return
+	}
+	decoded, err = io.ReadAll(gz)
+	_ = gz.Close()
+	return
+}
+
+// encodeGolden compresses the data for the golden file name given, if it has
+// the CompressedExtension
+func encodeGolden(name string, data []byte) (encoded []byte, err error) {
+	if !strings.HasSuffix(name, CompressedExtension) {
+		return data, nil
+	}
+	var buf bytes.Buffer
+	gz, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if _, err = gz.Write(data); err == nil {
+		err = gz.Close()
+	}
+	encoded = buf.Bytes()
+	return
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// mockT captures the failures and logs of the golden helpers under test
+type mockT struct {
+	testing.TB
+
+	name    string
+	errors  []string
+	logs    []string
+	fatal   bool
+	cleanup []func()
+	sync.Mutex
+}
+
+func newMockT(name string) *mockT {
+	return &mockT{name: name}
+}
+
+func (m *mockT) Helper() {}
+
+func (m *mockT) Name() string {
+	return m.name
+}
+
+func (m *mockT) Errorf(format string, args ...any) {
+	m.Lock()
+	defer m.Unlock()
+	m.errors = append(m.errors, fmt.Sprintf(format, args...))
+}
+
+func (m *mockT) Fatalf(format string, args ...any) {
+	m.Errorf(format, args...)
+	m.fatal = true
+}
+
+func (m *mockT) Logf(format string, args ...any) {
+	m.Lock()
+	defer m.Unlock()
+	m.logs = append(m.logs, fmt.Sprintf(format, args...))
+}
+
+func (m *mockT) Cleanup(fn func()) {
+	m.cleanup = append(m.cleanup, fn)
+}
+
+func (m *mockT) runCleanup() {
+	for idx := len(m.cleanup) - 1; idx >= 0; idx-- {
+		m.cleanup[idx]()
+	}
+	m.cleanup = nil
+}
+
+func (m *mockT) failed() string {
+	return strings.Join(m.errors, "\n")
+}
+
+// withUpdating runs fn with golden updates enabled
+func withUpdating(fn func()) {
+	withUpdateScope("true", fn)
+}
+
+// withUpdateScope runs fn with the UpdateFlag set to the value given
+func withUpdateScope(value string, fn func()) {
+	updateGoldens.value = value
+	defer func() { updateGoldens.value = "" }()
+	fn()
+}
+
+func TestGolden(t *testing.T) {
+
+	Convey("Updating", t, func() {
+		So(Updating(), ShouldBeFalse)
+		t.Setenv(UpdateEnv, "true")
+		So(Updating(), ShouldBeTrue)
+		t.Setenv(UpdateEnv, "")
+		withUpdating(func() {
+			So(Updating(), ShouldBeTrue)
+		})
+		So(Updating(), ShouldBeFalse)
+	})
+
+	Convey("Golden", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		mt := newMockT("TestGolden")
+		Golden(mt, tmpd, "missing.golden", "nope")
+		So(mt.failed(), ShouldContainSubstring, `golden file "missing.golden" not found`)
+
+		mt = newMockT("TestGolden")
+		withUpdating(func() {
+			Golden(mt, tmpd, "sub/output.golden", "one\ntwo\n")
+		})
+		So(mt.failed(), ShouldEqual, "")
+		So(mt.logs, ShouldEqual, []string{"updated golden file: sub/output.golden"})
+		So(tmpd.F("sub/output.golden"), ShouldEqual, "one\ntwo\n")
+
+		mt = newMockT("TestGolden")
+		Golden(mt, tmpd, "sub/output.golden", "one\ntwo\n")
+		So(mt.failed(), ShouldEqual, "")
+
+		mt = newMockT("TestGolden")
+		Golden(mt, tmpd, "sub/output.golden", "one\nthree\n")
+		So(mt.failed(), ShouldStartWith, `golden file "sub/output.golden" mismatch:`)
+		So(mt.failed(), ShouldContainSubstring, "\n+   2 | three\n")
+	})
+
+}
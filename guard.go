@@ -0,0 +1,315 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	// GuardFlag is the name of the command-line flag which enables the
+	// fixture integrity guard in Main
+	GuardFlag = "tdata.guard"
+	// GuardEnv is the environment variable equivalent of the GuardFlag, set
+	// to a true value, which also enables the guard from the first New call
+	// when Main is not used
+	GuardEnv = "TDATA_GUARD"
+)
+
+var guardFixtures = flag.Bool(GuardFlag, false, "fail if any tdata fixture files are added, removed or modified by the tests")
+
+// GuardChange describes a fixture file which was added, removed or modified
+// after the integrity guard hashed its TestData directory
+type GuardChange struct {
+	// Root is the TestData directory path
+	Root string
+	// Path is the slash-separated file path, relative to the Root
+	Path string
+	// Change is one of "added", "removed" or "modified"
+	Change string
+	// Test is the name of the test which was running when the change
+	// happened, or empty when that could not be determined
+	Test string
+}
+
+func (c *GuardChange) String() (text string) {
+	text = c.Change + ": " + c.Path
+	if c.Test != "" {
+		text += " (during " + c.Test + ")"
+	}
+	return
+}
+
+// guardActivity is the time a test was running, from when it was first noted
+// until it completed, end is zero while the test is still running
+type guardActivity struct {
+	test  string
+	start time.Time
+	end   time.Time
+}
+
+var guard = struct {
+	enabled atomic.Bool
+	sync.Mutex
+	baselines map[string]map[string]string
+	activity  []*guardActivity
+}{
+	baselines: make(map[string]map[string]string),
+}
+
+// Guard enables the fixture integrity guard, this is done by Main when the
+// GuardFlag (or GuardEnv) is given. The contents of every TestData directory
+// constructed so far are hashed immediately and any constructed later are
+// hashed by their first New call. VerifyGuard reports any changes made after
+// the hashing
+//
+// The guard is not armed while Updating, as golden files are expected to
+// change then
+func Guard() {
+	if Updating() {
+		return
+	}
+	guard.enabled.Store(true)
+	usage.Lock()
+	roots := make([]string, 0, len(usage.roots))
+	for root := range usage.roots {
+		roots = append(roots, root)
+	}
+	usage.Unlock()
+	for _, root := range roots {
+		guardRoot(root)
+	}
+}
+
+// guardRequested returns true if the GuardFlag is set or the GuardEnv is set
+// to a true value
+func guardRequested() (requested bool) {
+	if requested = *guardFixtures; !requested {
+		requested, _ = strconv.ParseBool(os.Getenv(GuardEnv))
+	}
+	return
+}
+
+// guardRoot hashes the TestData path, once, when the guard is enabled
+func guardRoot(root string) {
+	if !guard.enabled.Load() {
+		if !guardRequested() || Updating() {
+			return
+		}
+		guard.enabled.Store(true)
+	}
+	guard.Lock()
+	defer guard.Unlock()
+	if _, present := guard.baselines[root]; !present {
+		guard.baselines[root] = hashGuardTree(root)
+	}
+}
+
+// noteGuardTest records that the test is running, for attributing changes
+// found by VerifyGuard to tests. The tdata helpers which are given a
+// testing.TB call this on behalf of their callers
+func noteGuardTest(t testing.TB) {
+	if !guard.enabled.Load() {
+		return
+	}
+	guard.Lock()
+	defer guard.Unlock()
+	name := t.Name()
+	for _, activity := range guard.activity {
+		if activity.test == name && activity.end.IsZero() {
+			return
+		}
+	}
+	activity := &guardActivity{test: name, start: time.Now()}
+	guard.activity = append(guard.activity, activity)
+	t.Cleanup(func() {
+		guard.Lock()
+		defer guard.Unlock()
+		activity.end = time.Now()
+	})
+}
+
+// GuardTest enables the integrity guard, if not already enabled, and checks
+// for fixture changes once the test completes, failing the test with each
+// change found. Changes reported by GuardTest are not reported again by
+// VerifyGuard. Tests running in parallel are reported for each others'
+// changes, so GuardTest is most precise with sequential tests
+func GuardTest(t testing.TB) {
+	t.Helper()
+	if Updating() {
+		return
+	}
+	if !guard.enabled.Load() {
+		Guard()
+	}
+	noteGuardTest(t)
+	t.Cleanup(func() {
+		for _, change := range checkGuard(true) {
+			change.Test = t.Name()
+			t.Errorf("fixture %s in %s", change, change.Root)
+		}
+	})
+}
+
+// VerifyGuard rehashes each of the TestData directories hashed by the
+// integrity guard and returns the files added, removed or modified since,
+// sorted by Root and Path. The Test of each change is the test, having
+// called a tdata helper such as Golden, Snapshot or CaseRunner.Run, which was
+// running at the modification time of the file, or of its directory for
+// removed files. The Test is left empty unless exactly one test was running
+func VerifyGuard() (changes []*GuardChange) {
+	return checkGuard(false)
+}
+
+func checkGuard(rebaseline bool) (changes []*GuardChange) {
+	if !guard.enabled.Load() {
+		return
+	}
+	guard.Lock()
+	defer guard.Unlock()
+
+	roots := make([]string, 0, len(guard.baselines))
+	for root := range guard.baselines {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+
+	for _, root := range roots {
+		before := guard.baselines[root]
+		after := hashGuardTree(root)
+		var found []*GuardChange
+		for rel, sum := range after {
+			if previous, present := before[rel]; !present {
+				found = append(found, &GuardChange{Root: root, Path: rel, Change: "added"})
+			} else if previous != sum {
+				found = append(found, &GuardChange{Root: root, Path: rel, Change: "modified"})
+			}
+		}
+		for rel := range before {
+			if _, present := after[rel]; !present {
+				found = append(found, &GuardChange{Root: root, Path: rel, Change: "removed"})
+			}
+		}
+		sort.Slice(found, func(i, j int) bool { return found[i].Path < found[j].Path })
+		for _, change := range found {
+			change.Test = guardAttribute(root, change.Path)
+		}
+		changes = append(changes, found...)
+		if rebaseline {
+			guard.baselines[root] = after
+		}
+	}
+	return
+}
+
+// guardAttribute returns the name of the test which was running at the
+// modification time of the file, or of its closest existing parent directory,
+// or an empty name unless exactly one test was running at the time. A parent
+// test running its subtest is not counted separately from the subtest
+func guardAttribute(root, rel string) (test string) {
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	var info os.FileInfo
+	for {
+		var err error
+		if info, err = os.Lstat(path); err == nil {
+			break
+		} else if path == root {
+			return
+		}
+		path = filepath.Dir(path)
+	}
+	modified := info.ModTime()
+	var running []string
+	for _, activity := range guard.activity {
+		if !activity.start.After(modified) && (activity.end.IsZero() || !activity.end.Before(modified)) {
+			running = append(running, activity.test)
+		}
+	}
+	var innermost []string
+	for _, name := range running {
+		parent := false
+		for _, other := range running {
+			if strings.HasPrefix(other, name+"/") {
+				parent = true
+				break
+			}
+		}
+		if !parent {
+			innermost = append(innermost, name)
+		}
+	}
+	if len(innermost) == 1 {
+		test = innermost[0]
+	}
+	return
+}
+
+// hashGuardTree returns the sha256 of every file within root, keyed by the
+// slash-separated path relative to root. Symbolic links are hashed by their
+// target and pending golden files, along with the PendingIndexFile, are
+// skipped when Reviewing
+func hashGuardTree(root string) (sums map[string]string) {
+	sums = make(map[string]string)
+	reviewing := Reviewing()
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		if reviewing && (strings.HasSuffix(rel, PendingExtension) || rel == PendingIndexFile) {
+			// written on purpose by golden file mismatches
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			target, _ := os.Readlink(path)
+			sums[filepath.ToSlash(rel)] = "-> " + target
+			return nil
+		}
+		if data, ee := os.ReadFile(path); ee == nil {
+			sums[filepath.ToSlash(rel)] = sha256Hex(data)
+		} else {
+			sums[filepath.ToSlash(rel)] = "error: " + ee.Error()
+		}
+		return nil
+	})
+	return
+}
+
+// writeGuard prints the changes found by VerifyGuard to w, grouped by Root,
+// returning true if there were any
+func writeGuard(w io.Writer) (changed bool) {
+	var root string
+	for _, change := range VerifyGuard() {
+		if change.Root != root {
+			root = change.Root
+			_, _ = fmt.Fprintf(w, "tdata: fixture files in %s were changed by the tests:\n", root)
+		}
+		_, _ = fmt.Fprintf(w, "  %s\n", change)
+		changed = true
+	}
+	return
+}
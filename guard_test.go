@@ -0,0 +1,224 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// withGuard runs fn with the integrity guard state reset and td registered
+// as the only root, restoring the previous guard state afterwards
+func withGuard(td TData, fn func()) {
+	guard.Lock()
+	baselines, activity := guard.baselines, guard.activity
+	guard.baselines, guard.activity = make(map[string]map[string]string), nil
+	guard.Unlock()
+	enabled := guard.enabled.Load()
+	guard.enabled.Store(false)
+	withUsage(td, func() {
+		defer func() {
+			guard.Lock()
+			guard.baselines, guard.activity = baselines, activity
+			guard.Unlock()
+			guard.enabled.Store(enabled)
+		}()
+		fn()
+	})
+}
+
+// pauseGuard waits long enough for file modification times, which may have
+// a coarse resolution, to be after any test activity noted before
+func pauseGuard() {
+	time.Sleep(20 * time.Millisecond)
+}
+
+func TestGuard(t *testing.T) {
+
+	Convey("VerifyGuard", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"fixtures/keep.txt":        File("keep"),
+			"fixtures/edit.txt":        File("edit"),
+			"fixtures/nested/drop.txt": File("drop"),
+			"fixtures/.hidden":         File("hidden"),
+		}.Apply(tmpd), ShouldBeNil)
+		fixtures := &tdata{path: tmpd.Join("fixtures")}
+
+		withGuard(fixtures, func() {
+			// nothing is hashed until enabled
+			guardRoot(fixtures.Path())
+			So(VerifyGuard(), ShouldBeEmpty)
+			noteGuardTest(newMockT("TestIgnored"))
+
+			Guard()
+			So(VerifyGuard(), ShouldBeEmpty)
+
+			first := newMockT("TestFirst")
+			noteGuardTest(first)
+			noteGuardTest(first) // noted once while running
+			pauseGuard()
+			So(os.WriteFile(fixtures.Join("edit.txt"), []byte("edited"), 0644), ShouldBeNil)
+			So(os.WriteFile(fixtures.Join(".hidden"), []byte("changed"), 0644), ShouldBeNil)
+			pauseGuard()
+			first.runCleanup()
+			second := newMockT("TestSecond")
+			noteGuardTest(second)
+			noteGuardTest(newMockT("TestSecond/sub"))
+			pauseGuard()
+			So(os.WriteFile(fixtures.Join("added.txt"), []byte("added"), 0644), ShouldBeNil)
+			So(os.Remove(fixtures.Join("nested", "drop.txt")), ShouldBeNil)
+
+			changes := VerifyGuard()
+			So(changes, ShouldResemble, []*GuardChange{
+				{Root: fixtures.Path(), Path: ".hidden", Change: "modified", Test: "TestFirst"},
+				{Root: fixtures.Path(), Path: "added.txt", Change: "added", Test: "TestSecond/sub"},
+				{Root: fixtures.Path(), Path: "edit.txt", Change: "modified", Test: "TestFirst"},
+				{Root: fixtures.Path(), Path: "nested/drop.txt", Change: "removed", Test: "TestSecond/sub"},
+			})
+			So(changes[1].String(), ShouldEqual, "added: added.txt (during TestSecond/sub)")
+			So((&GuardChange{Path: "x", Change: "removed"}).String(), ShouldEqual, "removed: x")
+
+			// not rebaselined
+			So(VerifyGuard(), ShouldHaveLength, 4)
+		})
+	})
+
+	Convey("Attribution", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"fixtures/both.txt":  File("both"),
+			"fixtures/after.txt": File("after"),
+		}.Apply(tmpd), ShouldBeNil)
+		fixtures := &tdata{path: tmpd.Join("fixtures")}
+
+		withGuard(fixtures, func() {
+			Guard()
+			one, two := newMockT("TestOne"), newMockT("TestTwo")
+			noteGuardTest(one)
+			noteGuardTest(two)
+			pauseGuard()
+			So(os.WriteFile(fixtures.Join("both.txt"), []byte("changed"), 0644), ShouldBeNil)
+			pauseGuard()
+			one.runCleanup()
+			two.runCleanup()
+			pauseGuard()
+			// such as by a TestMain, after the tests
+			So(os.WriteFile(fixtures.Join("after.txt"), []byte("changed"), 0644), ShouldBeNil)
+
+			So(VerifyGuard(), ShouldResemble, []*GuardChange{
+				{Root: fixtures.Path(), Path: "after.txt", Change: "modified"},
+				{Root: fixtures.Path(), Path: "both.txt", Change: "modified"},
+			})
+		})
+	})
+
+	Convey("Requested", t, func() {
+		for value, requested := range map[string]bool{
+			"":      false,
+			"0":     false,
+			"false": false,
+			"nope":  false,
+			"1":     true,
+			"true":  true,
+		} {
+			t.Setenv(GuardEnv, value)
+			So(guardRequested(), ShouldEqual, requested)
+		}
+		t.Setenv(GuardEnv, "")
+	})
+
+	Convey("GuardTest", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{"fixtures/file.txt": File("file")}.Apply(tmpd), ShouldBeNil)
+		fixtures := &tdata{path: tmpd.Join("fixtures")}
+
+		withGuard(fixtures, func() {
+			clean := newMockT("TestClean")
+			GuardTest(clean)
+			clean.runCleanup()
+			So(clean.failed(), ShouldEqual, "")
+
+			dirty := newMockT("TestDirty")
+			GuardTest(dirty)
+			So(os.WriteFile(fixtures.Join("file.txt"), []byte("overwritten"), 0644), ShouldBeNil)
+			dirty.runCleanup()
+			So(dirty.failed(), ShouldEqual, "fixture modified: file.txt (during TestDirty) in "+fixtures.Path())
+
+			// already reported
+			So(VerifyGuard(), ShouldBeEmpty)
+
+			// pending golden files are expected while reviewing
+			withReviewing(func() {
+				So(os.WriteFile(fixtures.Join("file.txt.new"), []byte("pending"), 0644), ShouldBeNil)
+				So(VerifyGuard(), ShouldBeEmpty)
+			})
+			So(VerifyGuard(), ShouldHaveLength, 1)
+		})
+
+		withGuard(fixtures, func() {
+			withUpdating(func() {
+				updating := newMockT("TestUpdating")
+				GuardTest(updating)
+				So(updating.cleanup, ShouldBeEmpty)
+				So(guard.enabled.Load(), ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Main", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{"fixtures/file.txt": File("file")}.Apply(tmpd), ShouldBeNil)
+		fixtures := &tdata{path: tmpd.Join("fixtures")}
+
+		var output strings.Builder
+		var code int
+		withGuard(fixtures, func() {
+			t.Setenv(GuardEnv, "true")
+			code = runMain(&mockRunner{run: func() int {
+				noteGuardTest(newMockT("TestOverwrite"))
+				pauseGuard()
+				_ = os.WriteFile(fixtures.Join("file.txt"), []byte("overwritten"), 0644)
+				return 0
+			}}, &output)
+		})
+		So(code, ShouldEqual, 1)
+		So(output.String(), ShouldEqual, ""+
+			"tdata: fixture files in "+fixtures.Path()+" were changed by the tests:\n"+
+			"  modified: file.txt (during TestOverwrite)\n",
+		)
+
+		output.Reset()
+		t.Setenv(GuardEnv, "")
+		withGuard(fixtures, func() {
+			code = runMain(&mockRunner{run: func() int { return 0 }}, &output)
+		})
+		So(code, ShouldEqual, 0)
+		So(output.String(), ShouldEqual, "")
+	})
+
+}
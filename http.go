@@ -0,0 +1,387 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const (
+	// HTTPFixtureExtension is the file extension of the fixtures run by
+	// RunHTTPFixtures
+	HTTPFixtureExtension = ".http"
+	// HTTPFixtureSeparator is the line separating the request and expected
+	// response sections of an HTTP fixture
+	HTTPFixtureSeparator = "###"
+	// HTTPAnyValue is the expected value of a header which must be present
+	// but may have any value
+	HTTPAnyValue = "*"
+)
+
+// DefaultVolatileHeaders are the response headers written with the
+// HTTPAnyValue when updating HTTP fixtures, used when the HTTPOptions
+// VolatileHeaders is nil
+var DefaultVolatileHeaders = []string{"Date", "ETag", "Last-Modified"}
+
+// HTTPOptions configures the behaviour of GoldenHTTP and RunHTTPFixtures
+type HTTPOptions struct {
+	// VolatileHeaders are the response headers which change from run to run,
+	// written with the HTTPAnyValue when updating. When nil,
+	// DefaultVolatileHeaders is used
+	VolatileHeaders []string
+	// Normalizers are applied to the expected and actual response header
+	// values and text bodies before they are compared, and to the response
+	// written when updating
+	Normalizers []Normalizer
+}
+
+func (o *HTTPOptions) volatile(name string) (volatile bool) {
+	headers := o.VolatileHeaders
+	if headers == nil {
+		headers = DefaultVolatileHeaders
+	}
+	for _, header := range headers {
+		if http.CanonicalHeaderKey(header) == name {
+			return true
+		}
+	}
+	return
+}
+
+// HTTPFixture is a parsed HTTP fixture file, which contains a raw HTTP
+// request, a line with the HTTPFixtureSeparator and the expected response:
+// the status line, the headers to check and the body
+//
+// Example:
+//
+//	POST /api/items HTTP/1.1
+//	Content-Type: application/json
+//
+//	{"name": "thing"}
+//	###
+//	HTTP/1.1 201 Created
+//	Content-Type: application/json
+//	Date: *
+//
+//	{"id": 1, "name": "thing"}
+//
+// The protocol may be omitted from the request line and a missing Host
+// header defaults to "example.com". The request body is everything between
+// the blank line and the separator (less the final line ending) while the
+// response body is everything after the blank line, exactly
+type HTTPFixture struct {
+	// Request is the raw request section
+	Request string
+	// Expected is false when there is no response section
+	Expected bool
+	// Status is the expected response status code
+	Status int
+	// Header is the expected response headers, only the headers listed are
+	// checked and a value of HTTPAnyValue only checks that it is present
+	Header http.Header
+	// Body is the expected response body
+	Body []byte
+}
+
+// ParseHTTPFixture parses the contents of an HTTP fixture file
+func ParseHTTPFixture(data []byte) (fixture *HTTPFixture, err error) {
+	fixture = &HTTPFixture{}
+	request, response, found := splitHTTPFixture(string(data))
+	fixture.Request = request
+	if _, err = fixture.NewRequest(); err != nil || !found {
+		return
+	}
+
+	fixture.Expected = true
+	head, body := splitHTTPHead(response)
+	fixture.Body = []byte(body)
+	if len(head) == 0 {
+		err = fmt.Errorf("%w: missing response status line", ErrHTTPFixtureSyntax)
+		return
+	}
+	if fields := strings.Fields(head[0]); len(fields) < 2 || !strings.HasPrefix(fields[0], "HTTP/") {
+		err = fmt.Errorf("%w: invalid response status line: %q", ErrHTTPFixtureSyntax, head[0])
+		return
+	} else if fixture.Status, err = strconv.Atoi(fields[1]); err != nil {
+		err = fmt.Errorf("%w: invalid response status code: %q", ErrHTTPFixtureSyntax, fields[1])
+		return
+	}
+	fixture.Header = make(http.Header)
+	for _, line := range head[1:] {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			err = fmt.Errorf("%w: invalid response header: %q", ErrHTTPFixtureSyntax, line)
+			return
+		}
+		fixture.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return
+}
+
+// splitHTTPFixture splits the fixture at the first HTTPFixtureSeparator line
+func splitHTTPFixture(text string) (request, response string, found bool) {
+	for start := 0; start < len(text); {
+		end := strings.IndexByte(text[start:], '\n')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += start
+		}
+		if strings.TrimSuffix(text[start:end], "\r") == HTTPFixtureSeparator {
+			return trimLineEnding(text[:start]), text[min(end+1, len(text)):], true
+		}
+		start = end + 1
+	}
+	return trimLineEnding(text), "", false
+}
+
+// trimLineEnding removes a single trailing "\n" or "\r\n"
+func trimLineEnding(text string) (trimmed string) {
+	if trimmed = strings.TrimSuffix(text, "\n"); trimmed != text {
+		trimmed = strings.TrimSuffix(trimmed, "\r")
+	}
+	return
+}
+
+// splitHTTPHead splits a request or response section into the lines before
+// the first blank line and the body after it
+func splitHTTPHead(section string) (head []string, body string) {
+	for start := 0; start < len(section); {
+		end := strings.IndexByte(section[start:], '\n')
+		if end < 0 {
+			end = len(section)
+		} else {
+			end += start
+		}
+		line := strings.TrimSuffix(section[start:end], "\r")
+		if line == "" {
+			return head, section[min(end+1, len(section)):]
+		}
+		head = append(head, line)
+		start = end + 1
+	}
+	return
+}
+
+// NewRequest returns a new server request for the fixture's request section,
+// suitable for passing directly to an http.Handler
+func (f *HTTPFixture) NewRequest() (r *http.Request, err error) {
+	head, body := splitHTTPHead(f.Request)
+	if len(head) == 0 {
+		err = fmt.Errorf("%w: missing request line", ErrHTTPFixtureSyntax)
+		return
+	}
+	if fields := strings.Fields(head[0]); len(fields) == 2 {
+		head[0] += " HTTP/1.1"
+	}
+	raw := strings.Join(head, "\r\n") + "\r\n\r\n"
+	if r, err = http.ReadRequest(bufio.NewReader(strings.NewReader(raw))); err != nil {
+		err = fmt.Errorf("%w: %v", ErrHTTPFixtureSyntax, err)
+		return
+	}
+	if r.Host == "" {
+		r.Host = "example.com"
+	}
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Body = io.NopCloser(strings.NewReader(body))
+	r.ContentLength = int64(len(body))
+	return
+}
+
+// Compare returns a description of each difference between the fixture's
+// expected response and the given response, which is empty when the
+// response matches
+func (f *HTTPFixture) Compare(got *http.Response, body []byte, opts *HTTPOptions) (problems []string) {
+	if opts == nil {
+		opts = &HTTPOptions{}
+	}
+	if !f.Expected {
+		return []string{"missing expected response"}
+	}
+	if f.Status != got.StatusCode {
+		problems = append(problems, fmt.Sprintf("status: want %d, got %d", f.Status, got.StatusCode))
+	}
+
+	names := make([]string, 0, len(f.Header))
+	for name := range f.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		want, values := f.Header[name], got.Header.Values(name)
+		switch {
+		case len(values) == 0:
+			problems = append(problems, fmt.Sprintf("header %s: missing, want %q", name, strings.Join(want, ", ")))
+		case len(want) == 1 && want[0] == HTTPAnyValue:
+		default:
+			expected := Normalize(strings.Join(want, ", "), opts.Normalizers...)
+			actual := Normalize(strings.Join(values, ", "), opts.Normalizers...)
+			if expected != actual {
+				problems = append(problems, fmt.Sprintf("header %s: want %q, got %q", name, expected, actual))
+			}
+		}
+	}
+
+	if isText(f.Body) && isText(body) {
+		expected, actual := Normalize(string(f.Body), opts.Normalizers...), Normalize(string(body), opts.Normalizers...)
+		if expected != actual {
+			problems = append(problems, "body:\n"+textDiff(expected, actual))
+		}
+	} else if diff := HexDiff(f.Body, body); diff != "" {
+		problems = append(problems, "body:\n"+diff)
+	}
+	return
+}
+
+// Update replaces the fixture's expected response with the given response,
+// writing volatile headers with the HTTPAnyValue
+func (f *HTTPFixture) Update(got *http.Response, body []byte, opts *HTTPOptions) {
+	if opts == nil {
+		opts = &HTTPOptions{}
+	}
+	f.Expected, f.Status, f.Header = true, got.StatusCode, make(http.Header)
+	for name, values := range got.Header {
+		if opts.volatile(name) {
+			f.Header.Set(name, HTTPAnyValue)
+			continue
+		}
+		for _, value := range values {
+			f.Header.Add(name, Normalize(value, opts.Normalizers...))
+		}
+	}
+	f.Body = body
+	if isText(body) {
+		f.Body = []byte(Normalize(string(body), opts.Normalizers...))
+	}
+}
+
+// Bytes returns the fixture file contents, with the response headers sorted
+func (f *HTTPFixture) Bytes() (data []byte) {
+	var buf bytes.Buffer
+	buf.WriteString(f.Request)
+	if f.Expected {
+		buf.WriteString("\n" + HTTPFixtureSeparator + "\n")
+		buf.WriteString(fmt.Sprintf("HTTP/1.1 %d %s\n", f.Status, http.StatusText(f.Status)))
+		writeSortedHeader(&buf, f.Header)
+		buf.WriteString("\n")
+		buf.Write(f.Body)
+	}
+	return buf.Bytes()
+}
+
+// GoldenHTTP sends the request of the named HTTP fixture within td to the
+// handler and compares the response with the fixture's expected response,
+// failing the test if they differ. When Updating, the expected response
+// section of the fixture is rewritten with the actual response instead and
+// when Reviewing, a mismatched fixture has its pending replacement written.
+// As with Golden, the most specific variant of the fixture is used
+func GoldenHTTP(t testing.TB, td TData, name string, handler http.Handler, opts *HTTPOptions) {
+	t.Helper()
+	noteGuardTest(t)
+	checkChecksums(t, td)
+
+	resolved := ResolveGolden(td, name, goldenPlatform)
+	updating := updatingGolden(t, name, resolved)
+	name = resolved
+	data, err := readGolden(td, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("http fixture %q not found", name)
+		} else {
+			t.Errorf("error reading http fixture %q: %v", name, err)
+		}
+		return
+	}
+
+	// a malformed response section is not an error when it is about to be
+	// replaced, a malformed request always is
+	fixture, err := ParseHTTPFixture(data)
+	r, re := fixture.NewRequest()
+	if re != nil || (err != nil && !updating) {
+		t.Errorf("error parsing http fixture %q: %v", name, err)
+		return
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, r)
+	response := recorder.Result()
+	body := recorder.Body.Bytes()
+
+	if updating {
+		fixture.Update(response, body, opts)
+		if written, err := updateGolden(t, td, name, fixture.Bytes()); err != nil {
+			t.Fatalf("error updating http fixture %q: %v", name, err)
+		} else if written {
+			t.Logf("updated http fixture: %s", name)
+		}
+		return
+	}
+
+	if problems := fixture.Compare(response, body, opts); len(problems) > 0 {
+		t.Errorf("http fixture %q mismatch:\n%s", name, strings.Join(problems, "\n"))
+		if Reviewing() {
+			fixture.Update(response, body, opts)
+			reviewPending(t, td, name, fixture.Bytes())
+		}
+	} else if Reviewing() {
+		discardPending(td, name)
+	}
+}
+
+// RunHTTPFixtures runs GoldenHTTP, in a subtest named after the file, for
+// each of the HTTP fixture files within the dirname of td
+func RunHTTPFixtures(t *testing.T, td TData, dirname string, handler http.Handler, opts *HTTPOptions) {
+	t.Helper()
+	var found bool
+	for _, path := range td.LF(dirname) {
+		if filepath.Ext(path) != HTTPFixtureExtension {
+			continue
+		}
+		found = true
+		name := strings.TrimPrefix(path, td.Path()+string(filepath.Separator))
+		t.Run(strings.TrimSuffix(filepath.Base(path), HTTPFixtureExtension), func(t *testing.T) {
+			GoldenHTTP(t, td, name, handler, opts)
+		})
+	}
+	if !found {
+		t.Errorf("no %s fixtures found in %q", HTTPFixtureExtension, dirname)
+	}
+}
+
+// writeSortedHeader writes each header line, sorted by name
+func writeSortedHeader(w io.StringWriter, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			_, _ = w.WriteString(name + ": " + value + "\n")
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newTestHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = fmt.Fprintf(w, "hello %s\n", r.URL.Query().Get("name"))
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, "%s %s %s", r.Method, r.Host, body)
+	})
+	return mux
+}
+
+func TestHTTP(t *testing.T) {
+
+	Convey("ParseHTTPFixture", t, func() {
+		fixture, err := ParseHTTPFixture([]byte("" +
+			"POST /echo\n" +
+			"Host: api.example.org\n" +
+			"Content-Type: text/plain\n" +
+			"\n" +
+			"payload\n" +
+			"###\n" +
+			"HTTP/1.1 201 Created\n" +
+			"Content-Type: text/plain\n" +
+			"X-Multi: a\n" +
+			"X-Multi: b\n" +
+			"\n" +
+			"body",
+		))
+		So(err, ShouldBeNil)
+		So(fixture.Expected, ShouldBeTrue)
+		So(fixture.Status, ShouldEqual, 201)
+		So(fixture.Header, ShouldResemble, http.Header{
+			"Content-Type": {"text/plain"},
+			"X-Multi":      {"a", "b"},
+		})
+		So(string(fixture.Body), ShouldEqual, "body")
+
+		r, err := fixture.NewRequest()
+		So(err, ShouldBeNil)
+		So(r.Method, ShouldEqual, "POST")
+		So(r.Host, ShouldEqual, "api.example.org")
+		So(r.RequestURI, ShouldEqual, "/echo")
+		body, _ := io.ReadAll(r.Body)
+		So(string(body), ShouldEqual, "payload")
+
+		fixture, err = ParseHTTPFixture([]byte("GET /hello\r\n"))
+		So(err, ShouldBeNil)
+		So(fixture.Expected, ShouldBeFalse)
+		r, err = fixture.NewRequest()
+		So(err, ShouldBeNil)
+		So(r.Host, ShouldEqual, "example.com")
+
+		for _, data := range []string{
+			"",
+			"not a request\n",
+			"GET /\n###\n",
+			"GET /\n###\nHTTP/1.1 two hundred\n",
+			"GET /\n###\nHTTP/1.1 200 OK\nnot a header\n",
+		} {
+			_, err = ParseHTTPFixture([]byte(data))
+			So(errors.Is(err, ErrHTTPFixtureSyntax), ShouldBeTrue)
+		}
+	})
+
+	Convey("GoldenHTTP", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		handler := newTestHandler()
+
+		So(os.WriteFile(tmpd.Join("hello.http"), []byte("GET /hello?name=world\n"), 0644), ShouldBeNil)
+		mt := newMockT("TestHTTP")
+		GoldenHTTP(mt, tmpd, "hello.http", handler, nil)
+		So(mt.failed(), ShouldEqual, "http fixture \"hello.http\" mismatch:\nmissing expected response")
+
+		mt = newMockT("TestHTTP")
+		withUpdating(func() {
+			GoldenHTTP(mt, tmpd, "hello.http", handler, nil)
+		})
+		So(mt.failed(), ShouldEqual, "")
+		So(tmpd.F("hello.http"), ShouldEqual, ""+
+			"GET /hello?name=world\n"+
+			"###\n"+
+			"HTTP/1.1 200 OK\n"+
+			"Content-Type: text/plain\n"+
+			"Date: *\n"+
+			"\n"+
+			"hello world\n",
+		)
+
+		mt = newMockT("TestHTTP")
+		GoldenHTTP(mt, tmpd, "hello.http", handler, nil)
+		So(mt.failed(), ShouldEqual, "")
+
+		So(os.WriteFile(tmpd.Join("hello.http"), []byte(""+
+			"GET /hello?name=world\n"+
+			"###\n"+
+			"HTTP/1.1 200 OK\n"+
+			"Content-Type: text/html\n"+
+			"X-Missing: yes\n"+
+			"\n"+
+			"hello there\n",
+		), 0644), ShouldBeNil)
+		mt = newMockT("TestHTTP")
+		GoldenHTTP(mt, tmpd, "hello.http", handler, nil)
+		So(mt.failed(), ShouldEqual, ""+
+			"http fixture \"hello.http\" mismatch:\n"+
+			"header Content-Type: want \"text/html\", got \"text/plain\"\n"+
+			"header X-Missing: missing, want \"yes\"\n"+
+			"body:\n"+
+			textDiff("hello there\n", "hello world\n"),
+		)
+
+		mt = newMockT("TestHTTP")
+		GoldenHTTP(mt, tmpd, "hello.http", handler, &HTTPOptions{Normalizers: []Normalizer{
+			Redact(`there|world`, "<name>"),
+			Redact(`text/\w+`, "text/*"),
+		}})
+		So(mt.failed(), ShouldEqual, "http fixture \"hello.http\" mismatch:\nheader X-Missing: missing, want \"yes\"")
+
+		mt = newMockT("TestHTTP")
+		GoldenHTTP(mt, tmpd, "missing.http", handler, nil)
+		So(mt.failed(), ShouldEqual, "http fixture \"missing.http\" not found")
+	})
+
+	Convey("RunHTTPFixtures", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(os.MkdirAll(tmpd.Join("api"), 0755), ShouldBeNil)
+		So(os.WriteFile(tmpd.Join("api", "echo.http"), []byte(""+
+			"PUT /echo\n"+
+			"Content-Type: text/plain\n"+
+			"\n"+
+			"data\n"+
+			"###\n"+
+			"HTTP/1.1 201 Created\n"+
+			"\n"+
+			"PUT example.com data",
+		), 0644), ShouldBeNil)
+		So(os.WriteFile(tmpd.Join("api", "notes.txt"), []byte("ignored"), 0644), ShouldBeNil)
+
+		var names []string
+		RunHTTPFixtures(t, tmpd, "api", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			names = append(names, r.Method)
+			newTestHandler().ServeHTTP(w, r)
+		}), nil)
+		So(names, ShouldEqual, []string{"PUT"})
+	})
+
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var (
+	// ImageDiffColor is the color used to highlight differing pixels in the
+	// diff images written by GoldenImage
+	ImageDiffColor color.Color = color.RGBA{R: 255, A: 255}
+)
+
+// ImageOptions configures the behaviour of CompareImages and GoldenImage
+type ImageOptions struct {
+	// Tolerance is the maximum difference, per 8-bit color channel (including
+	// alpha), for two pixels to be considered the same
+	Tolerance uint8
+	// MaxDiffPixels is the number of differing pixels allowed before the
+	// images are considered different
+	MaxDiffPixels int
+	// Artifacts is where GoldenImage writes the actual and diff-highlight
+	// images when the comparison fails, as `<name>.got.png` and
+	// `<name>.diff.png` at the same relative path as the golden file. When
+	// nil, a new TempData is created (and not destroyed) for each failure
+	Artifacts TData
+}
+
+// ImageReport is the result of CompareImages
+type ImageReport struct {
+	// SizeMismatch is true when the images have different dimensions, pixels
+	// outside either image are counted as differing
+	SizeMismatch bool
+	// DiffPixels is the number of pixels which differ beyond the Tolerance
+	DiffPixels int
+	// TotalPixels is the number of pixels compared
+	TotalPixels int
+	// Diff is a diff-highlight image: differing pixels are ImageDiffColor and
+	// all others are a faded grayscale of the wanted image
+	Diff *image.RGBA
+	// Equal is true when the images are the same size and DiffPixels does
+	// not exceed the MaxDiffPixels allowed
+	Equal bool
+}
+
+// String returns a one line summary of the report
+func (r *ImageReport) String() (summary string) {
+	summary = fmt.Sprintf("%d of %d pixels differ", r.DiffPixels, r.TotalPixels)
+	if r.SizeMismatch {
+		summary = "image sizes differ, " + summary
+	}
+	return
+}
+
+// CompareImages compares two images pixel by pixel. A nil opts is the same as
+// the zero ImageOptions
+func CompareImages(got, want image.Image, opts *ImageOptions) (report *ImageReport) {
+	if opts == nil {
+		opts = &ImageOptions{}
+	}
+	gb, wb := got.Bounds(), want.Bounds()
+	width, height := max(gb.Dx(), wb.Dx()), max(gb.Dy(), wb.Dy())
+
+	report = &ImageReport{
+		SizeMismatch: gb.Dx() != wb.Dx() || gb.Dy() != wb.Dy(),
+		TotalPixels:  width * height,
+		Diff:         image.NewRGBA(image.Rect(0, 0, width, height)),
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			gp, wp := image.Pt(gb.Min.X+x, gb.Min.Y+y), image.Pt(wb.Min.X+x, wb.Min.Y+y)
+			if !gp.In(gb) || !wp.In(wb) || !pixelsMatch(got.At(gp.X, gp.Y), want.At(wp.X, wp.Y), opts.Tolerance) {
+				report.DiffPixels++
+				report.Diff.Set(x, y, ImageDiffColor)
+				continue
+			}
+			gray := color.GrayModel.Convert(want.At(wp.X, wp.Y)).(color.Gray)
+			faded := 192 + gray.Y/4
+			report.Diff.Set(x, y, color.RGBA{R: faded, G: faded, B: faded, A: 255})
+		}
+	}
+
+	report.Equal = !report.SizeMismatch && report.DiffPixels <= opts.MaxDiffPixels
+	return
+}
+
+func pixelsMatch(a, b color.Color, tolerance uint8) (match bool) {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	for _, pair := range [][2]uint32{{ar, br}, {ag, bg}, {ab, bb}, {aa, ba}} {
+		// reduce the 16-bit channels to 8-bit
+		x, y := int(pair[0]>>8), int(pair[1]>>8)
+		if diff := x - y; diff > int(tolerance) || -diff > int(tolerance) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadImage decodes the named PNG, JPEG or GIF file within td
+func ReadImage(td TData, name string) (img image.Image, err error) {
+	var data []byte
+	if data, err = os.ReadFile(td.Join(name)); err == nil {
+		img, _, err = image.Decode(bytes.NewReader(data))
+	}
+	return
+}
+
+// CompareImageFiles decodes the named got and want files within td and
+// compares them with CompareImages
+func CompareImageFiles(td TData, got, want string, opts *ImageOptions) (report *ImageReport, err error) {
+	var gi, wi image.Image
+	if gi, err = ReadImage(td, got); err != nil {
+		return
+	} else if wi, err = ReadImage(td, want); err != nil {
+		return
+	}
+	report = CompareImages(gi, wi, opts)
+	return
+}
+
+// GoldenImage compares the got image with the named golden image file
+// within td using CompareImages. On failure, the got image and the
+// diff-highlight image are written to the ImageOptions Artifacts directory.
+// When Updating, the golden file is written with got encoded as a PNG
+func GoldenImage(t testing.TB, td TData, name string, got image.Image, opts *ImageOptions) {
+	t.Helper()
+	if opts == nil {
+		opts = &ImageOptions{}
+	}
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, got); err != nil {
+		t.Fatalf("error encoding image for golden file %q: %v", name, err)
+		return
+	}
+
+	golden(t, td, name, encoded.Bytes(), func(data []byte) (problem string) {
+		want, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Sprintf("error decoding golden image: %v", err)
+		}
+		report := CompareImages(got, want, opts)
+		if report.Equal {
+			return
+		}
+		problem = report.String()
+		if artifacts, ee := writeImageArtifacts(opts.Artifacts, name, encoded.Bytes(), report.Diff); ee != nil {
+			problem += fmt.Sprintf("\nerror writing image artifacts: %v", ee)
+		} else {
+			problem += "\nartifacts: " + strings.Join(artifacts, ", ")
+		}
+		return
+	})
+}
+
+// writeImageArtifacts writes the got and diff images of the named golden image
+// to td, or to a new temporary directory when td is nil. The artifacts mirror
+// the relative path of the golden file, so that golden images of the same
+// name in different directories do not overwrite each other's artifacts
+func writeImageArtifacts(td TData, name string, got []byte, diff image.Image) (written []string, err error) {
+	if td == nil {
+		if td, err = NewTempData("", "tdata-artifacts-*"); err != nil {
+			return
+		}
+	}
+	base := strings.TrimSuffix(filepath.FromSlash(name), filepath.Ext(name))
+
+	var encoded bytes.Buffer
+	if err = png.Encode(&encoded, diff); err != nil {
+		return
+	}
+	for suffix, data := range map[string][]byte{".got.png": got, ".diff.png": encoded.Bytes()} {
+		filename := td.Join(base + suffix)
+		if err = os.MkdirAll(filepath.Dir(filename), DefaultDirMode); err != nil {
+			return
+		} else if err = os.WriteFile(filename, data, 0644); err != nil {
+			return
+		}
+		written = append(written, filename)
+	}
+	sort.Strings(written)
+	return
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newTestImage(width, height int, fill color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, fill)
+		}
+	}
+	return img
+}
+
+func TestImage(t *testing.T) {
+
+	gray := color.RGBA{R: 100, G: 100, B: 100, A: 255}
+
+	Convey("CompareImages", t, func() {
+		want := newTestImage(4, 4, gray)
+		got := newTestImage(4, 4, gray)
+
+		report := CompareImages(got, want, nil)
+		So(report.Equal, ShouldBeTrue)
+		So(report.DiffPixels, ShouldEqual, 0)
+		So(report.TotalPixels, ShouldEqual, 16)
+
+		got.SetRGBA(1, 1, color.RGBA{R: 102, G: 100, B: 98, A: 255})
+		got.SetRGBA(2, 2, color.RGBA{R: 200, G: 100, B: 100, A: 255})
+		report = CompareImages(got, want, nil)
+		So(report.Equal, ShouldBeFalse)
+		So(report.DiffPixels, ShouldEqual, 2)
+		So(report.String(), ShouldEqual, "2 of 16 pixels differ")
+		So(report.Diff.RGBAAt(1, 1), ShouldResemble, ImageDiffColor)
+		So(report.Diff.RGBAAt(0, 0), ShouldNotResemble, ImageDiffColor)
+
+		report = CompareImages(got, want, &ImageOptions{Tolerance: 2})
+		So(report.DiffPixels, ShouldEqual, 1)
+		So(report.Equal, ShouldBeFalse)
+		report = CompareImages(got, want, &ImageOptions{Tolerance: 2, MaxDiffPixels: 1})
+		So(report.Equal, ShouldBeTrue)
+
+		report = CompareImages(newTestImage(5, 4, gray), want, &ImageOptions{MaxDiffPixels: 100})
+		So(report.Equal, ShouldBeFalse)
+		So(report.SizeMismatch, ShouldBeTrue)
+		So(report.DiffPixels, ShouldEqual, 4)
+		So(report.String(), ShouldEqual, "image sizes differ, 4 of 20 pixels differ")
+
+		// bounds need not start at the origin
+		offset := newTestImage(6, 6, gray).SubImage(image.Rect(2, 2, 6, 6))
+		So(CompareImages(offset, want, nil).Equal, ShouldBeTrue)
+	})
+
+	Convey("CompareImageFiles", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		var buf bytes.Buffer
+		So(png.Encode(&buf, newTestImage(2, 2, gray)), ShouldBeNil)
+		So(os.WriteFile(tmpd.Join("a.png"), buf.Bytes(), 0644), ShouldBeNil)
+		So(os.WriteFile(tmpd.Join("b.png"), buf.Bytes(), 0644), ShouldBeNil)
+		So(os.WriteFile(tmpd.Join("bad.png"), []byte("nope"), 0644), ShouldBeNil)
+
+		report, err := CompareImageFiles(tmpd, "a.png", "b.png", nil)
+		So(err, ShouldBeNil)
+		So(report.Equal, ShouldBeTrue)
+		_, err = CompareImageFiles(tmpd, "bad.png", "b.png", nil)
+		So(err, ShouldNotBeNil)
+		_, err = CompareImageFiles(tmpd, "a.png", "missing.png", nil)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("GoldenImage", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		artifacts, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer artifacts.Destroy()
+
+		img := newTestImage(3, 3, gray)
+		mt := newMockT("TestImage")
+		withUpdating(func() {
+			GoldenImage(mt, tmpd, "screen.png", img, nil)
+		})
+		So(mt.failed(), ShouldEqual, "")
+		stored, err := ReadImage(tmpd, "screen.png")
+		So(err, ShouldBeNil)
+		So(CompareImages(stored, img, nil).Equal, ShouldBeTrue)
+
+		mt = newMockT("TestImage")
+		GoldenImage(mt, tmpd, "screen.png", img, nil)
+		So(mt.failed(), ShouldEqual, "")
+
+		img.SetRGBA(0, 0, color.RGBA{A: 255})
+		mt = newMockT("TestImage")
+		GoldenImage(mt, tmpd, "screen.png", img, &ImageOptions{Artifacts: artifacts})
+		So(mt.failed(), ShouldEqual, "golden file \"screen.png\" mismatch:\n"+
+			"1 of 9 pixels differ\n"+
+			"artifacts: "+artifacts.Join("screen.diff.png")+", "+artifacts.Join("screen.got.png"))
+		diff, err := ReadImage(artifacts, "screen.diff.png")
+		So(err, ShouldBeNil)
+		So(diff.At(0, 0), ShouldResemble, ImageDiffColor)
+		So(artifacts.E("screen.got.png"), ShouldBeTrue)
+
+		// same base names in different directories
+		for _, dir := range []string{"a", "b"} {
+			mt = newMockT("TestImage")
+			withUpdating(func() {
+				GoldenImage(mt, tmpd, dir+"/icon.png", newTestImage(2, 2, gray), nil)
+			})
+			So(mt.failed(), ShouldEqual, "")
+			mt = newMockT("TestImage")
+			GoldenImage(mt, tmpd, dir+"/icon.png", img, &ImageOptions{Artifacts: artifacts})
+			So(mt.failed(), ShouldEndWith, "artifacts: "+
+				artifacts.Join(dir, "icon.diff.png")+", "+artifacts.Join(dir, "icon.got.png"))
+		}
+		So(artifacts.E("a/icon.got.png"), ShouldBeTrue)
+		So(artifacts.E("b/icon.got.png"), ShouldBeTrue)
+	})
+
+}
@@ -0,0 +1,319 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// JSONOptions configures the behaviour of CompareJSON and GoldenJSON
+type JSONOptions struct {
+	// UnorderedArrays is a list of JSON Pointer (RFC 6901) paths of arrays
+	// where the order of the elements is not significant. A segment of `*`
+	// matches any single key or index, so `/items/*/tags` covers the tags
+	// of every item
+	UnorderedArrays []string
+	// FloatTolerance is the maximum absolute difference between two numbers
+	// for them to be considered equal, numbers are otherwise compared exactly
+	// (so large integers beyond the precision of a float64 are not equal)
+	FloatTolerance float64
+}
+
+func (o *JSONOptions) unordered(pointer string) (unordered bool) {
+	segments := strings.Split(pointer, "/")
+	for _, pattern := range o.UnorderedArrays {
+		if pattern == "/" {
+			pattern = ""
+		}
+		patterns := strings.Split(pattern, "/")
+		if len(patterns) != len(segments) {
+			continue
+		}
+		matched := true
+		for idx := range patterns {
+			if patterns[idx] != "*" && patterns[idx] != segments[idx] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return
+}
+
+// JSONDifference describes a single difference found by CompareJSON
+type JSONDifference struct {
+	// Pointer is the JSON Pointer (RFC 6901) path of the difference
+	Pointer string
+	// Want is the compact JSON of the expected value, empty when the value
+	// is unexpected
+	Want string
+	// Got is the compact JSON of the actual value, empty when the value is
+	// missing
+	Got string
+}
+
+// String returns a single line description of the difference
+func (d JSONDifference) String() (text string) {
+	pointer := d.Pointer
+	if pointer == "" {
+		pointer = "/"
+	}
+	switch {
+	case d.Got == "":
+		return fmt.Sprintf("%s: missing, want %s", pointer, d.Want)
+	case d.Want == "":
+		return fmt.Sprintf("%s: unexpected, got %s", pointer, d.Got)
+	}
+	return fmt.Sprintf("%s: want %s, got %s", pointer, d.Want, d.Got)
+}
+
+// CompareJSON semantically compares two JSON documents, ignoring whitespace
+// and object key order. A nil opts is the same as the zero JSONOptions. An
+// error is returned if either document is not valid JSON
+func CompareJSON(got, want []byte, opts *JSONOptions) (diffs []JSONDifference, err error) {
+	if opts == nil {
+		opts = &JSONOptions{}
+	}
+	var g, w any
+	if g, err = decodeJSON(got); err != nil {
+		err = fmt.Errorf("got: %w", err)
+		return
+	} else if w, err = decodeJSON(want); err != nil {
+		err = fmt.Errorf("want: %w", err)
+		return
+	}
+	diffs = compareJSON(opts, "", w, g)
+	return
+}
+
+// CanonicalJSON returns the data re-encoded with sorted object keys, two
+// space indentation, no HTML escaping and a trailing newline
+func CanonicalJSON(data []byte) (canonical []byte, err error) {
+	var value any
+	if value, err = decodeJSON(data); err != nil {
+		return
+	}
+	return encodeJSON(value, "  ")
+}
+
+// GoldenJSON compares the got JSON document with the named golden file within
+// td using CompareJSON, failing the test with the list of differences found.
+// When Updating, the golden file is written with the CanonicalJSON of got.
+// Any normalizers given are applied to the text of both documents before
+// they are decoded
+func GoldenJSON(t testing.TB, td TData, name string, got []byte, opts *JSONOptions, options ...GoldenOption) {
+	t.Helper()
+	cfg := newGoldenConfig(options)
+	canonical, err := CanonicalJSON([]byte(cfg.normalize(string(got))))
+	if err != nil {
+		t.Fatalf("error decoding JSON for golden file %q: %v", name, err)
+		return
+	}
+	golden(t, td, name, canonical, func(want []byte) (problem string) {
+		diffs, ee := CompareJSON(canonical, []byte(cfg.normalize(string(want))), opts)
+		if ee != nil {
+			return ee.Error()
+		}
+		var lines []string
+		for _, diff := range diffs {
+			lines = append(lines, diff.String())
+		}
+		return strings.Join(lines, "\n")
+	})
+}
+
+func decodeJSON(data []byte) (value any, err error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err = dec.Decode(&value); err != nil {
+		return
+	} else if dec.More() {
+		err = fmt.Errorf("unexpected data after the top-level value")
+	}
+	return
+}
+
+func encodeJSON(value any, indent string) (data []byte, err error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err = enc.Encode(value); err == nil {
+		data = buf.Bytes()
+		if indent == "" {
+			data = bytes.TrimSuffix(data, []byte("\n"))
+		}
+	}
+	return
+}
+
+func compactJSON(value any) (text string) {
+	data, _ := encodeJSON(value, "")
+	return string(data)
+}
+
+func jsonPointer(parent, key string) (pointer string) {
+	key = strings.ReplaceAll(key, "~", "~0")
+	key = strings.ReplaceAll(key, "/", "~1")
+	return parent + "/" + key
+}
+
+func compareJSON(opts *JSONOptions, pointer string, want, got any) (diffs []JSONDifference) {
+	mismatch := []JSONDifference{{Pointer: pointer, Want: compactJSON(want), Got: compactJSON(got)}}
+
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			return mismatch
+		}
+		keys := make(map[string]struct{})
+		for key := range w {
+			keys[key] = struct{}{}
+		}
+		for key := range g {
+			keys[key] = struct{}{}
+		}
+		var sorted []string
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+		for _, key := range sorted {
+			wv, wok := w[key]
+			gv, gok := g[key]
+			switch {
+			case !gok:
+				diffs = append(diffs, JSONDifference{Pointer: jsonPointer(pointer, key), Want: compactJSON(wv)})
+			case !wok:
+				diffs = append(diffs, JSONDifference{Pointer: jsonPointer(pointer, key), Got: compactJSON(gv)})
+			default:
+				diffs = append(diffs, compareJSON(opts, jsonPointer(pointer, key), wv, gv)...)
+			}
+		}
+		return
+
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			return mismatch
+		}
+		if opts.unordered(pointer) {
+			return compareUnorderedJSON(opts, pointer, w, g)
+		}
+		for idx := 0; idx < max(len(w), len(g)); idx++ {
+			p := jsonPointer(pointer, strconv.Itoa(idx))
+			switch {
+			case idx >= len(g):
+				diffs = append(diffs, JSONDifference{Pointer: p, Want: compactJSON(w[idx])})
+			case idx >= len(w):
+				diffs = append(diffs, JSONDifference{Pointer: p, Got: compactJSON(g[idx])})
+			default:
+				diffs = append(diffs, compareJSON(opts, p, w[idx], g[idx])...)
+			}
+		}
+		return
+
+	case json.Number:
+		g, ok := got.(json.Number)
+		if !ok {
+			return mismatch
+		} else if w == g || jsonNumbersEqual(w, g, opts.FloatTolerance) {
+			return
+		}
+		return mismatch
+	}
+
+	if want != got {
+		return mismatch
+	}
+	return
+}
+
+// jsonNumbersEqual returns true if the numbers differ by no more than the
+// tolerance given. The numbers are compared exactly, as rationals, so that
+// large integers and long decimals do not lose precision to float64
+func jsonNumbersEqual(want, got json.Number, tolerance float64) (equal bool) {
+	w, wok := new(big.Rat).SetString(want.String())
+	g, gok := new(big.Rat).SetString(got.String())
+	if !wok || !gok {
+		return false
+	}
+	diff := new(big.Rat).Sub(w, g)
+	diff.Abs(diff)
+	limit := new(big.Rat)
+	if tolerance > 0 && limit.SetFloat64(tolerance) == nil {
+		// an infinite tolerance
+		return true
+	}
+	return diff.Cmp(limit) <= 0
+}
+
+// compareUnorderedJSON pairs each want element with a distinct got element
+// which it matches, using a maximum bipartite matching so that an element
+// matching more than one other (within the FloatTolerance, for example) does
+// not take the only match of another element
+func compareUnorderedJSON(opts *JSONOptions, pointer string, want, got []any) (diffs []JSONDifference) {
+	candidates := make([][]int, len(want))
+	for wi, wv := range want {
+		for gi, gv := range got {
+			if len(compareJSON(opts, jsonPointer(pointer, strconv.Itoa(wi)), wv, gv)) == 0 {
+				candidates[wi] = append(candidates[wi], gi)
+			}
+		}
+	}
+
+	// owner is the want element each got element is paired with, or -1
+	owner := make([]int, len(got))
+	for gi := range owner {
+		owner[gi] = -1
+	}
+	var augment func(wi int, seen []bool) (paired bool)
+	augment = func(wi int, seen []bool) (paired bool) {
+		for _, gi := range candidates[wi] {
+			if seen[gi] {
+				continue
+			}
+			seen[gi] = true
+			if owner[gi] < 0 || augment(owner[gi], seen) {
+				owner[gi] = wi
+				return true
+			}
+		}
+		return false
+	}
+
+	for wi, wv := range want {
+		if !augment(wi, make([]bool, len(got))) {
+			diffs = append(diffs, JSONDifference{Pointer: jsonPointer(pointer, strconv.Itoa(wi)), Want: compactJSON(wv)})
+		}
+	}
+	for gi, gv := range got {
+		if owner[gi] < 0 {
+			diffs = append(diffs, JSONDifference{Pointer: jsonPointer(pointer, strconv.Itoa(gi)), Got: compactJSON(gv)})
+		}
+	}
+	return
+}
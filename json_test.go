@@ -0,0 +1,162 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJSON(t *testing.T) {
+
+	Convey("CompareJSON", t, func() {
+		diffs, err := CompareJSON(
+			[]byte(`{"b": [1, 2], "a": {"x": "y"}}`),
+			[]byte("{\n  \"a\": {\"x\": \"y\"},\n  \"b\": [1, 2]\n}"),
+			nil,
+		)
+		So(err, ShouldBeNil)
+		So(diffs, ShouldBeEmpty)
+
+		diffs, err = CompareJSON(
+			[]byte(`{"list": [3, 1], "num": 1.0000001, "new": null, "a/b": true, "type": [1]}`),
+			[]byte(`{"list": [1, 2, 3], "num": 1, "gone": "x", "a/b": false, "type": {}}`),
+			nil,
+		)
+		So(err, ShouldBeNil)
+		var lines []string
+		for _, diff := range diffs {
+			lines = append(lines, diff.String())
+		}
+		So(lines, ShouldEqual, []string{
+			"/a~1b: want false, got true",
+			"/gone: missing, want \"x\"",
+			"/list/0: want 1, got 3",
+			"/list/1: want 2, got 1",
+			"/list/2: missing, want 3",
+			"/new: unexpected, got null",
+			"/num: want 1, got 1.0000001",
+			"/type: want {}, got [1]",
+		})
+
+		diffs, err = CompareJSON(
+			[]byte(`{"list": [3, 1], "num": 1.0000001}`),
+			[]byte(`{"list": [1, 2, 3], "num": 1}`),
+			&JSONOptions{UnorderedArrays: []string{"/list"}, FloatTolerance: 0.001},
+		)
+		So(err, ShouldBeNil)
+		So(diffs, ShouldEqual, []JSONDifference{{Pointer: "/list/1", Want: "2"}})
+
+		diffs, err = CompareJSON(
+			[]byte(`[{"tags": ["b", "a"]}, {"tags": ["c"]}]`),
+			[]byte(`[{"tags": ["a", "b"]}, {"tags": ["c"]}]`),
+			&JSONOptions{UnorderedArrays: []string{"/*/tags"}},
+		)
+		So(err, ShouldBeNil)
+		So(diffs, ShouldBeEmpty)
+
+		// unordered arrays within the elements of an unordered array
+		diffs, err = CompareJSON(
+			[]byte(`{"items": [{"id": 1, "tags": ["b", "a"]}, {"id": 2, "tags": ["d", "c"]}]}`),
+			[]byte(`{"items": [{"id": 2, "tags": ["c", "d"]}, {"id": 1, "tags": ["a", "b"]}]}`),
+			&JSONOptions{UnorderedArrays: []string{"/items", "/items/*/tags"}},
+		)
+		So(err, ShouldBeNil)
+		So(diffs, ShouldBeEmpty)
+		diffs, err = CompareJSON(
+			[]byte(`{"items": [{"id": 1, "tags": ["b", "a"]}]}`),
+			[]byte(`{"items": [{"id": 1, "tags": ["a", "b"]}]}`),
+			&JSONOptions{UnorderedArrays: []string{"/items"}},
+		)
+		So(err, ShouldBeNil)
+		So(diffs, ShouldHaveLength, 2)
+
+		diffs, err = CompareJSON([]byte(`[2, 1]`), []byte(`[1, 2]`), &JSONOptions{UnorderedArrays: []string{"/"}})
+		So(err, ShouldBeNil)
+		So(diffs, ShouldBeEmpty)
+
+		// elements matching more than one other are paired to match them all
+		diffs, err = CompareJSON([]byte(`[1.5, 1.0]`), []byte(`[1.0, 1.6]`), &JSONOptions{UnorderedArrays: []string{"/"}, FloatTolerance: 0.5})
+		So(err, ShouldBeNil)
+		So(diffs, ShouldBeEmpty)
+		diffs, err = CompareJSON([]byte(`[1.5, 3]`), []byte(`[1.0, 1.6]`), &JSONOptions{UnorderedArrays: []string{"/"}, FloatTolerance: 0.5})
+		So(err, ShouldBeNil)
+		So(diffs, ShouldEqual, []JSONDifference{{Pointer: "/1", Want: "1.6"}, {Pointer: "/1", Got: "3"}})
+
+		// numbers are compared without losing precision
+		diffs, err = CompareJSON([]byte(`9007199254740993`), []byte(`9007199254740992`), nil)
+		So(err, ShouldBeNil)
+		So(diffs, ShouldEqual, []JSONDifference{{Pointer: "", Want: "9007199254740992", Got: "9007199254740993"}})
+		diffs, err = CompareJSON([]byte(`9007199254740993`), []byte(`9007199254740992`), &JSONOptions{FloatTolerance: 0.5})
+		So(err, ShouldBeNil)
+		So(diffs, ShouldHaveLength, 1)
+		diffs, err = CompareJSON([]byte(`9007199254740993`), []byte(`9007199254740992`), &JSONOptions{FloatTolerance: 1})
+		So(err, ShouldBeNil)
+		So(diffs, ShouldBeEmpty)
+		diffs, err = CompareJSON([]byte(`1.0`), []byte(`1`), nil)
+		So(err, ShouldBeNil)
+		So(diffs, ShouldBeEmpty)
+
+		_, err = CompareJSON([]byte(`{`), []byte(`{}`), nil)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldStartWith, "got: ")
+		_, err = CompareJSON([]byte(`{}`), []byte(`{} {}`), nil)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldStartWith, "want: ")
+	})
+
+	Convey("CanonicalJSON", t, func() {
+		canonical, err := CanonicalJSON([]byte(`{"b":1e3,"a":["<html>",{"d":null,"c":12345678901234567890}]}`))
+		So(err, ShouldBeNil)
+		So(string(canonical), ShouldEqual, `{
+  "a": [
+    "<html>",
+    {
+      "c": 12345678901234567890,
+      "d": null
+    }
+  ],
+  "b": 1e3
+}
+`)
+	})
+
+	Convey("GoldenJSON", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		mt := newMockT("TestJSON")
+		withUpdating(func() {
+			GoldenJSON(mt, tmpd, "data.json", []byte(`{"z":1,"a":[1,2]}`), nil)
+		})
+		So(mt.failed(), ShouldEqual, "")
+		So(tmpd.F("data.json"), ShouldEqual, "{\n  \"a\": [\n    1,\n    2\n  ],\n  \"z\": 1\n}\n")
+
+		mt = newMockT("TestJSON")
+		GoldenJSON(mt, tmpd, "data.json", []byte(`{"a":[2,1],"z":1}`), &JSONOptions{UnorderedArrays: []string{"/a"}})
+		So(mt.failed(), ShouldEqual, "")
+
+		mt = newMockT("TestJSON")
+		GoldenJSON(mt, tmpd, "data.json", []byte(`{"a":[1,2],"z":2}`), nil)
+		So(mt.failed(), ShouldEqual, "golden file \"data.json\" mismatch:\n/z: want 1, got 2")
+
+		mt = newMockT("TestJSON")
+		GoldenJSON(mt, tmpd, "data.json", []byte(`{`), nil)
+		So(mt.fatal, ShouldBeTrue)
+	})
+
+}
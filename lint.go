@@ -0,0 +1,262 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// LintStray is the check for editor, merge and operating system files
+	// which are not fixtures, such as `.DS_Store`, `*~` or `*.orig`
+	LintStray = "stray"
+	// LintEmptyDir is the check for empty directories, which git does not
+	// track and so are missing from other checkouts
+	LintEmptyDir = "empty-dir"
+	// LintPending is the check for pending golden files which were neither
+	// accepted nor rejected
+	LintPending = "pending"
+	// LintCaseCollision is the check for names within the same directory
+	// which differ only by case, which collide on the case-insensitive
+	// filesystems of macOS and Windows
+	LintCaseCollision = "case-collision"
+	// LintWindowsName is the check for names which are reserved on Windows,
+	// such as `CON` or `aux.txt`, contain characters which Windows does not
+	// allow or end with a dot or space
+	LintWindowsName = "windows-name"
+	// LintLongPath is the check for paths longer than the MaxPathLength and
+	// for names longer than 255 bytes
+	LintLongPath = "long-path"
+	// LintNonUTF8 is the check for names which are not valid UTF-8
+	LintNonUTF8 = "non-utf8"
+	// LintSize is the check for files larger than the MaxFileSize
+	LintSize = "size"
+	// LintNewline is the check for text files without a trailing newline or
+	// with a mix of CRLF and LF line endings
+	LintNewline = "newline"
+)
+
+const (
+	// DefaultLintMaxPathLength is the default LintOptions.MaxPathLength,
+	// leaving room within the 260 character MAX_PATH of Windows for the
+	// location of the checkout itself
+	DefaultLintMaxPathLength = 160
+	// DefaultLintMaxFileSize is the default LintOptions.MaxFileSize, 1 MiB
+	DefaultLintMaxFileSize int64 = 1 << 20
+	// lintMaxNameLength is the longest file name, in bytes, supported by
+	// common filesystems
+	lintMaxNameLength = 255
+)
+
+// LintOptions configures the checks made by Lint
+type LintOptions struct {
+	// Skip lists the names of checks which are not made
+	Skip []string
+	// MaxPathLength is the longest path, in characters and relative to the
+	// TData, which is not an issue. Zero uses DefaultLintMaxPathLength
+	MaxPathLength int
+	// MaxFileSize is the largest file size, in bytes, which is not an issue.
+	// Zero uses DefaultLintMaxFileSize and a negative size allows any size
+	MaxFileSize int64
+}
+
+// LintIssue is a single problem reported by Lint
+type LintIssue struct {
+	// Path is the slash-separated path relative to the TData
+	Path string
+	// Check is the name of the check reporting the issue
+	Check string
+	// Message describes the issue
+	Message string
+}
+
+func (i *LintIssue) String() (text string) {
+	return i.Path + ": " + i.Message + " (" + i.Check + ")"
+}
+
+// strayNames are the names of files which are never fixtures
+var strayNames = []string{".DS_Store", "Thumbs.db", "desktop.ini"}
+
+// strayPatterns are the path.Match patterns of files which are never fixtures
+var strayPatterns = []string{"*~", ".#*", "#*#", "*.swp", "*.swo", "*.orig", "*.rej", "*.bak"}
+
+// Lint checks all the files and directories within td for problems which
+// make for poor fixtures, returning the issues found sorted by Path and
+// Check. Many of the checks are for problems with checking out the fixtures
+// on other operating systems, all checks are made the same on every platform.
+// A nil opts is the same as the zero LintOptions
+func Lint(td TData, opts *LintOptions) (issues []*LintIssue, err error) {
+	if opts == nil {
+		opts = &LintOptions{}
+	}
+	maxPath, maxSize := opts.MaxPathLength, opts.MaxFileSize
+	if maxPath <= 0 {
+		maxPath = DefaultLintMaxPathLength
+	}
+	if maxSize == 0 {
+		maxSize = DefaultLintMaxFileSize
+	}
+	report := func(rel, check, message string) {
+		if !slices.Contains(opts.Skip, check) {
+			issues = append(issues, &LintIssue{Path: rel, Check: check, Message: message})
+		}
+	}
+
+	root := td.Path()
+	var index map[string]bool
+	if index, err = readPendingIndex(root); err != nil {
+		return
+	}
+	if err = filepath.WalkDir(root, func(abs string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, abs)
+		rel = filepath.ToSlash(rel)
+		name := d.Name()
+
+		if d.IsDir() {
+			entries, ee := os.ReadDir(abs)
+			if ee != nil {
+				return ee
+			}
+			lintCaseCollisions(rel, entries, report)
+			if abs == root {
+				return nil
+			}
+			lintName(rel, name, maxPath, report)
+			if len(entries) == 0 {
+				report(rel, LintEmptyDir, "empty directory is not tracked by git")
+			}
+			return nil
+		}
+
+		lintName(rel, name, maxPath, report)
+		if strings.HasSuffix(name, PendingExtension) && isPending(root, abs, index) {
+			report(rel, LintPending, "pending golden file was not accepted or rejected")
+		} else if isStrayName(name) {
+			report(rel, LintStray, "stray file is not a fixture")
+		}
+		if d.Type().IsRegular() {
+			return lintFile(rel, abs, maxSize, report)
+		}
+		return nil
+	}); err != nil {
+		return
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Path != issues[j].Path {
+			return issues[i].Path < issues[j].Path
+		}
+		return issues[i].Check < issues[j].Check
+	})
+	return
+}
+
+func isStrayName(name string) (stray bool) {
+	if slices.Contains(strayNames, name) {
+		return true
+	}
+	for _, pattern := range strayPatterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return
+}
+
+// windowsReserved are the device names which Windows reserves, with or
+// without an extension
+var windowsReserved = []string{
+	"CON", "PRN", "AUX", "NUL",
+	"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+	"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9",
+}
+
+// windowsInvalid are the characters which Windows does not allow in names,
+// along with all the control characters
+const windowsInvalid = `<>:"\|?*`
+
+func lintName(rel, name string, maxPath int, report func(rel, check, message string)) {
+	if !utf8.ValidString(name) {
+		report(rel, LintNonUTF8, fmt.Sprintf("name %q is not valid UTF-8", name))
+	}
+
+	base, _, _ := strings.Cut(name, ".")
+	if slices.Contains(windowsReserved, strings.ToUpper(strings.TrimRight(base, " "))) {
+		report(rel, LintWindowsName, fmt.Sprintf("name %q is reserved on Windows", name))
+	} else if idx := strings.IndexFunc(name, func(r rune) bool {
+		return r < 0x20 || strings.ContainsRune(windowsInvalid, r)
+	}); idx >= 0 {
+		report(rel, LintWindowsName, fmt.Sprintf("name %q contains %q, which is not allowed on Windows", name, name[idx]))
+	} else if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		report(rel, LintWindowsName, fmt.Sprintf("name %q ends with a dot or space, which Windows removes", name))
+	}
+
+	if len(name) > lintMaxNameLength {
+		report(rel, LintLongPath, fmt.Sprintf("name is %d bytes long, more than %d", len(name), lintMaxNameLength))
+	} else if length := utf8.RuneCountInString(rel); length > maxPath {
+		report(rel, LintLongPath, fmt.Sprintf("path is %d characters long, more than %d", length, maxPath))
+	}
+}
+
+// lintCaseCollisions reports each directory entry whose name differs only by
+// case from an earlier entry
+func lintCaseCollisions(dir string, entries []fs.DirEntry, report func(rel, check, message string)) {
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		folded := strings.ToLower(entry.Name())
+		if first, present := seen[folded]; present {
+			report(path.Join(dir, entry.Name()), LintCaseCollision, fmt.Sprintf("name differs only by case from %q", first))
+			continue
+		}
+		seen[folded] = entry.Name()
+	}
+}
+
+func lintFile(rel, abs string, maxSize int64, report func(rel, check, message string)) (err error) {
+	var info fs.FileInfo
+	if info, err = os.Stat(abs); err != nil {
+		return
+	} else if maxSize > 0 && info.Size() > maxSize {
+		report(rel, LintSize, fmt.Sprintf("file is %d bytes, more than %d", info.Size(), maxSize))
+		return
+	} else if info.Size() == 0 {
+		return
+	}
+
+	var data []byte
+	if data, err = os.ReadFile(abs); err != nil || !isText(data) {
+		return
+	}
+	crlf := bytes.Count(data, []byte("\r\n"))
+	if lf := bytes.Count(data, []byte("\n")) - crlf; crlf > 0 && lf > 0 {
+		report(rel, LintNewline, fmt.Sprintf("file has mixed line endings, %d CRLF and %d LF", crlf, lf))
+	}
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		report(rel, LintNewline, "text file does not end with a newline")
+	}
+	return
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLint(t *testing.T) {
+
+	Convey("Lint", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"clean/input.txt":        File("input\n"),
+			"clean/output.golden":    File("output\n"),
+			"stray/.DS_Store":        File(""),
+			"stray/input.txt~":       File(""),
+			"stray/input.txt.orig":   File(""),
+			"stray/.input.txt.swp":   File(""),
+			"pending/out.golden":     File("out\n"),
+			"pending/out.golden.new": File("new\n"),
+			"pending/data.new":       File("data\n"),
+			"empty":                  Dir(),
+		}.Apply(tmpd), ShouldBeNil)
+
+		issues, err := Lint(tmpd, nil)
+		So(err, ShouldBeNil)
+		So(issues, ShouldResemble, []*LintIssue{
+			{Path: "empty", Check: LintEmptyDir, Message: "empty directory is not tracked by git"},
+			{Path: "pending/out.golden.new", Check: LintPending, Message: "pending golden file was not accepted or rejected"},
+			{Path: "stray/.DS_Store", Check: LintStray, Message: "stray file is not a fixture"},
+			{Path: "stray/.input.txt.swp", Check: LintStray, Message: "stray file is not a fixture"},
+			{Path: "stray/input.txt.orig", Check: LintStray, Message: "stray file is not a fixture"},
+			{Path: "stray/input.txt~", Check: LintStray, Message: "stray file is not a fixture"},
+		})
+		So(issues[0].String(), ShouldEqual, "empty: empty directory is not tracked by git (empty-dir)")
+
+		issues, err = Lint(tmpd, &LintOptions{Skip: []string{LintStray, LintEmptyDir}})
+		So(err, ShouldBeNil)
+		So(issues, ShouldHaveLength, 1)
+		So(issues[0].Check, ShouldEqual, LintPending)
+
+		_, err = Lint(&tdata{path: tmpd.Join("missing")}, nil)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Portability", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		long := strings.Repeat("d", 100)
+		So(Tree{
+			"case/Readme.md":           File("upper\n"),
+			"case/README.md":           File("upper\n"),
+			"case/readme.md":           File("lower\n"),
+			"Case/file.txt":            File("dir\n"),
+			"windows/con":              File("\n"),
+			"windows/Aux.tar.gz":       File(""),
+			"windows/lpt1 .txt":        File(""),
+			"windows/console.txt":      File("\n"),
+			"windows/what?.txt":        File("\n"),
+			"windows/tab\there":        File("\n"),
+			"windows/back\\slash":      File("\n"),
+			"windows/trailing.":        File("\n"),
+			long + "/" + long:          File("\n"),
+			"encoding/latin1-\xe9.txt": File("\n"),
+			"size/large.bin":           File(strings.Repeat("\x00", 2048)),
+			"size/small.bin":           File("\x00"),
+			"newline/missing.txt":      File("no newline"),
+			"newline/mixed.txt":        File("one\r\ntwo\nthree\r\n"),
+			"newline/crlf.txt":         File("one\r\ntwo\r\n"),
+			"newline/binary.bin":       File("\x00no newline"),
+			"newline/empty.txt":        File(""),
+		}.Apply(tmpd), ShouldBeNil)
+
+		issues, err := Lint(tmpd, &LintOptions{MaxFileSize: 1024})
+		So(err, ShouldBeNil)
+		var lines []string
+		for _, issue := range issues {
+			lines = append(lines, issue.String())
+		}
+		So(lines, ShouldEqual, []string{
+			"case: name differs only by case from \"Case\" (case-collision)",
+			"case/Readme.md: name differs only by case from \"README.md\" (case-collision)",
+			"case/readme.md: name differs only by case from \"README.md\" (case-collision)",
+			long + "/" + long + ": path is 201 characters long, more than 160 (long-path)",
+			"encoding/latin1-\xe9.txt: name \"latin1-\\xe9.txt\" is not valid UTF-8 (non-utf8)",
+			"newline/missing.txt: text file does not end with a newline (newline)",
+			"newline/mixed.txt: file has mixed line endings, 2 CRLF and 1 LF (newline)",
+			"size/large.bin: file is 2048 bytes, more than 1024 (size)",
+			"windows/Aux.tar.gz: name \"Aux.tar.gz\" is reserved on Windows (windows-name)",
+			"windows/back\\slash: name \"back\\\\slash\" contains '\\\\', which is not allowed on Windows (windows-name)",
+			"windows/con: name \"con\" is reserved on Windows (windows-name)",
+			"windows/lpt1 .txt: name \"lpt1 .txt\" is reserved on Windows (windows-name)",
+			"windows/tab\there: name \"tab\\there\" contains '\\t', which is not allowed on Windows (windows-name)",
+			"windows/trailing.: name \"trailing.\" ends with a dot or space, which Windows removes (windows-name)",
+			"windows/what?.txt: name \"what?.txt\" contains '?', which is not allowed on Windows (windows-name)",
+		})
+
+		issues, err = Lint(tmpd, &LintOptions{
+			MaxPathLength: 300,
+			MaxFileSize:   -1,
+			Skip:          []string{LintCaseCollision, LintWindowsName, LintNonUTF8, LintNewline},
+		})
+		So(err, ShouldBeNil)
+		So(issues, ShouldBeEmpty)
+
+		// names this long cannot be created on most filesystems
+		var reported []string
+		lintName("n", strings.Repeat("n", 256), DefaultLintMaxPathLength, func(rel, check, message string) {
+			reported = append(reported, message)
+		})
+		So(reported, ShouldEqual, []string{"name is 256 bytes long, more than 255"})
+	})
+
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+// mainRunner is the part of testing.M used by Main
+type mainRunner interface {
+	Run() (code int)
+}
+
+// Main is a TestMain wrapper which runs the tests and then reports on the
+// fixtures used, exiting with the result:
+//
+//	func TestMain(m *testing.M) {
+//		tdata.Main(m)
+//	}
+//
+// When the UsageFlag (or UsageEnv) is set, fixture usage is tracked and the
+// fixture files which were never used are listed, along with a JSON report
+// written to the path given
+//
+// When the GuardFlag (or GuardEnv) is set, the TestData directories are
+// hashed before the tests run and any fixture files added, removed or
+// modified by the tests are listed and fail the run
+//
+// Any TestData directory which failed the verification of its ChecksumsFile
+// is listed and fails the run
+//
+// When Reviewing, the pending golden files written during the tests are
+// listed for review
+//
+// When DryRun, the golden files which would have been updated are listed
+// along with the number of lines added and removed
+func Main(m *testing.M) {
+	os.Exit(runMain(m, os.Stdout))
+}
+
+func runMain(m mainRunner, w io.Writer) (code int) {
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+
+	reportPath := usageReportPath()
+	if reportPath != "" {
+		TrackUsage()
+	}
+
+	guarded := guardRequested() && !Updating()
+	if guarded {
+		Guard()
+	}
+
+	code = m.Run()
+
+	if guarded && writeGuard(w) {
+		code = max(code, 1)
+	}
+
+	if writeChecksumFailures(w) {
+		code = max(code, 1)
+	}
+
+	if DryRun() {
+		writeDryRunSummary(w)
+	} else if Reviewing() {
+		writePendingSummary(w)
+	}
+
+	if reportPath != "" {
+		if err := writeUsage(w, reportPath); err != nil {
+			_, _ = fmt.Fprintf(w, "tdata: error writing usage report: %v\n", err)
+			code = max(code, 1)
+		}
+	}
+	return
+}
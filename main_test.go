@@ -0,0 +1,127 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type mockRunner struct {
+	run func() (code int)
+}
+
+func (m *mockRunner) Run() (code int) {
+	return m.run()
+}
+
+func TestMainRunner(t *testing.T) {
+
+	Convey("Usage Report", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"fixtures/used.txt":   File("used"),
+			"fixtures/unused.txt": File("unused"),
+		}.Apply(tmpd), ShouldBeNil)
+		fixtures := &tdata{path: tmpd.Join("fixtures")}
+
+		defer func(value string) { *usageReport = value }(*usageReport)
+		*usageReport = tmpd.Join("usage.json")
+
+		var output strings.Builder
+		var code int
+		withUsage(fixtures, func() {
+			code = runMain(&mockRunner{run: func() int {
+				_ = fixtures.F("used.txt")
+				return 3
+			}}, &output)
+		})
+		So(code, ShouldEqual, 3)
+		So(output.String(), ShouldEqual, ""+
+			"tdata: 1 of 2 fixture files in "+fixtures.Path()+" were never used:\n"+
+			"  unused.txt\n",
+		)
+
+		var report struct {
+			Reports []*UsageReport `json:"reports"`
+		}
+		So(json.Unmarshal([]byte(tmpd.F("usage.json")), &report), ShouldBeNil)
+		So(report.Reports, ShouldResemble, []*UsageReport{{
+			Root:   fixtures.Path(),
+			Used:   []string{"used.txt"},
+			Unused: []string{"unused.txt"},
+		}})
+
+		*usageReport = tmpd.Join("missing", "usage.json")
+		output.Reset()
+		withUsage(fixtures, func() {
+			code = runMain(&mockRunner{run: func() int { return 0 }}, &output)
+		})
+		So(code, ShouldEqual, 1)
+		So(output.String(), ShouldContainSubstring, "tdata: error writing usage report: ")
+
+		*usageReport = ""
+		output.Reset()
+		So(runMain(&mockRunner{run: func() int { return 0 }}, &output), ShouldEqual, 0)
+		So(output.String(), ShouldEqual, "")
+	})
+
+	Convey("Pending Summary", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{"out.golden": File("old\n")}.Apply(tmpd), ShouldBeNil)
+
+		var output strings.Builder
+		var code int
+		withReviewing(func() {
+			code = runMain(&mockRunner{run: func() int {
+				Golden(newMockT("TestOut"), tmpd, "out.golden", "new\n")
+				return 1
+			}}, &output)
+		})
+		So(code, ShouldEqual, 1)
+		So(output.String(), ShouldStartWith, "tdata: 1 golden files have pending changes to review:\n")
+		So(output.String(), ShouldContainSubstring, "out.golden.new (TestOut)\n")
+	})
+
+	Convey("Dry Run Summary", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{"out.golden": File("old\n")}.Apply(tmpd), ShouldBeNil)
+
+		var output strings.Builder
+		var code int
+		withDryRun(func() {
+			code = runMain(&mockRunner{run: func() int {
+				Golden(newMockT("TestOut"), tmpd, "out.golden", "new\n")
+				return 0
+			}}, &output)
+		})
+		So(code, ShouldEqual, 0)
+		So(output.String(), ShouldEqual, ""+
+			"tdata: dry run, 1 golden files would change:\n"+
+			"  "+tmpd.Join("out.golden")+": +1 -1 lines (TestOut)\n",
+		)
+		So(tmpd.F("out.golden"), ShouldEqual, "old\n")
+	})
+
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// ManifestEntry describes a single path within a Manifest
+type ManifestEntry struct {
+	// Path is the slash-separated path relative to the manifest root
+	Path string
+	// Type is the kind of entry
+	Type EntryType
+	// Mode is the permission bits, zero when modes are ignored
+	Mode fs.FileMode
+	// Size is the length of the file contents (or symlink target), always
+	// zero for directories
+	Size int64
+	// Sum is the hex-encoded SHA-256 of the file contents (or symlink
+	// target), always empty for directories
+	Sum string
+}
+
+// String returns the manifest line for this entry, which has the following
+// space-separated fields: type, octal mode, size, sha256 and path. Fields
+// which do not apply to the entry are a single dash
+func (e ManifestEntry) String() (line string) {
+	mode, size, sum := "-", "-", "-"
+	if e.Mode != 0 {
+		mode = fmt.Sprintf("%04o", uint32(e.Mode.Perm()))
+	}
+	if e.Type != TypeDir {
+		size = strconv.FormatInt(e.Size, 10)
+		sum = e.Sum
+	}
+	return strings.Join([]string{e.Type.String(), mode, size, sum, e.Path}, " ")
+}
+
+// Manifest is a deterministic, path-sorted description of the shape of a
+// directory tree, suitable for storing as a reviewable golden file
+type Manifest []ManifestEntry
+
+// NewManifest generates a Manifest for all the entries within td. The opts
+// are the same as for CompareTrees, with IgnoreMode recording all modes as
+// zero. A nil opts is the same as the zero CompareOptions
+func NewManifest(td TData, opts *CompareOptions) (m Manifest, err error) {
+	if opts == nil {
+		opts = &CompareOptions{}
+	}
+
+	var entries map[string]treeEntryInfo
+	if entries, err = listTreeEntries(td, opts); err != nil {
+		return
+	}
+
+	for rel, entry := range entries {
+		me := ManifestEntry{Path: rel, Type: entryTypeOf(entry.info.Mode())}
+		if !opts.IgnoreMode && me.Type != TypeSymlink {
+			me.Mode = entry.info.Mode().Perm()
+		}
+		switch me.Type {
+		case TypeSymlink:
+			var link string
+			if link, err = os.Readlink(entry.abs); err != nil {
+				return
+			}
+			me.Size = int64(len(link))
+			me.Sum = sha256Hex([]byte(link))
+		case TypeFile:
+			var fh *os.File
+			if fh, err = os.Open(entry.abs); err != nil {
+				return
+			}
+			trackUsage(entry.abs)
+			hash := sha256.New()
+			me.Size, err = io.Copy(hash, fh)
+			_ = fh.Close()
+			if err != nil {
+				return
+			}
+			me.Sum = hex.EncodeToString(hash.Sum(nil))
+		}
+		m = append(m, me)
+	}
+
+	m.Sort()
+	return
+}
+
+// ParseManifest parses the output of Manifest.String
+func ParseManifest(text string) (m Manifest, err error) {
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 5)
+		if len(fields) != 5 {
+			err = fmt.Errorf("%w: line %d: expected 5 fields", ErrManifestSyntax, lineNo)
+			return
+		}
+		me := ManifestEntry{Path: fields[4]}
+		switch fields[0] {
+		case "file":
+			me.Type = TypeFile
+		case "dir":
+			me.Type = TypeDir
+		case "symlink":
+			me.Type = TypeSymlink
+		default:
+			err = fmt.Errorf("%w: line %d: unknown type %q", ErrManifestSyntax, lineNo, fields[0])
+			return
+		}
+		if fields[1] != "-" {
+			var mode uint64
+			if mode, err = strconv.ParseUint(fields[1], 8, 32); err != nil {
+				err = fmt.Errorf("%w: line %d: invalid mode %q", ErrManifestSyntax, lineNo, fields[1])
+				return
+			}
+			me.Mode = fs.FileMode(mode).Perm()
+		}
+		if fields[2] != "-" {
+			if me.Size, err = strconv.ParseInt(fields[2], 10, 64); err != nil {
+				err = fmt.Errorf("%w: line %d: invalid size %q", ErrManifestSyntax, lineNo, fields[2])
+				return
+			}
+		}
+		if fields[3] != "-" {
+			me.Sum = fields[3]
+		}
+		m = append(m, me)
+	}
+	err = scanner.Err()
+	return
+}
+
+// Sort sorts the Manifest entries by path
+func (m Manifest) Sort() {
+	sort.SliceStable(m, func(i, j int) bool {
+		return m[i].Path < m[j].Path
+	})
+}
+
+// String returns the text form of the Manifest, one entry per line
+func (m Manifest) String() (text string) {
+	var buf strings.Builder
+	for _, entry := range m {
+		buf.WriteString(entry.String() + "\n")
+	}
+	return buf.String()
+}
+
+// GoldenManifest generates a Manifest of the tree given and compares it with
+// the named golden file within td, updating the golden file instead when
+// Updating. Any normalizers given apply to the text of the manifests
+func GoldenManifest(t testing.TB, td TData, name string, tree TData, opts *CompareOptions, options ...GoldenOption) {
+	t.Helper()
+	m, err := NewManifest(tree, opts)
+	if err != nil {
+		t.Fatalf("error generating manifest for %q: %v", tree.Path(), err)
+		return
+	}
+	cfg := newGoldenConfig(options)
+	got := cfg.normalize(m.String())
+	golden(t, td, name, []byte(got), func(want []byte) (problem string) {
+		var expected Manifest
+		if expected, err = ParseManifest(cfg.normalize(string(want))); err != nil {
+			return err.Error()
+		}
+		expected.Sort()
+		if text := expected.String(); text != got {
+			problem = textDiff(text, got)
+		}
+		return
+	})
+}
+
+func sha256Hex(data []byte) (sum string) {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestManifest(t *testing.T) {
+
+	Convey("NewManifest", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"b/file.txt": File("test file\n"),
+			"a.sh":       File("#!/bin/sh\n").WithMode(0755),
+			"link":       Symlink("a.sh"),
+			".hidden":    File(""),
+		}.Apply(tmpd), ShouldBeNil)
+
+		m, err := NewManifest(tmpd, nil)
+		So(err, ShouldBeNil)
+		text := m.String()
+		So(text, ShouldEqual, ""+
+			"file 0755 10 a8076d3d28d21e02012b20eaf7dbf75409a6277134439025f282e368e3305abf a.sh\n"+
+			"dir 0755 - - b\n"+
+			"file 0644 10 55f8718109829bf506b09d8af615b9f107a266e19f7a311039d1035f180b22d4 b/file.txt\n"+
+			"symlink - 4 "+sha256Hex([]byte("a.sh"))+" link\n",
+		)
+
+		parsed, err := ParseManifest(text)
+		So(err, ShouldBeNil)
+		So(parsed, ShouldEqual, m)
+
+		m, err = NewManifest(tmpd, &CompareOptions{Hidden: true, IgnoreMode: true, Ignore: []string{"b"}})
+		So(err, ShouldBeNil)
+		So(m.String(), ShouldStartWith, "file - 0 "+sha256Hex(nil)+" .hidden\nfile - 10 ")
+	})
+
+	Convey("ParseManifest Errors", t, func() {
+		for _, text := range []string{
+			"file 0644 10 abc",
+			"other 0644 10 abc path",
+			"file 0999 10 abc path",
+			"file 0644 ten abc path",
+		} {
+			_, err := ParseManifest(text)
+			So(errors.Is(err, ErrManifestSyntax), ShouldBeTrue)
+		}
+	})
+
+	Convey("GoldenManifest", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		mt := newMockT("TestManifest")
+		withUpdating(func() {
+			GoldenManifest(mt, tmpd, "testdata.manifest", New(), &CompareOptions{IgnoreMode: true})
+		})
+		So(mt.failed(), ShouldEqual, "")
+		So(tmpd.F("testdata.manifest"), ShouldEqual, ""+
+			"dir - - - dir\n"+
+			"file - 10 "+sha256Hex([]byte("test file\n"))+" file.txt\n",
+		)
+
+		mt = newMockT("TestManifest")
+		GoldenManifest(mt, tmpd, "testdata.manifest", New(), &CompareOptions{IgnoreMode: true})
+		So(mt.failed(), ShouldEqual, "")
+
+		mt = newMockT("TestManifest")
+		GoldenManifest(mt, tmpd, "testdata.manifest", New(), &CompareOptions{Hidden: true, IgnoreMode: true})
+		So(mt.failed(), ShouldContainSubstring, "\n+   2 | file - 0 "+sha256Hex(nil)+" dir/.gitkeep\n")
+	})
+
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// TestDataPlaceholder replaces TestData paths in ReplaceRoots
+	TestDataPlaceholder = "$TESTDATA"
+	// TempDataPlaceholder replaces TempData paths in ReplaceRoots
+	TempDataPlaceholder = "$TEMPDATA"
+	// TDataPlaceholder replaces any other TData paths in ReplaceRoots
+	TDataPlaceholder = "$TDATA"
+)
+
+// Normalizer is a func which transforms text before it is compared, used to
+// scrub the volatile parts of output such as temporary paths and timestamps.
+// Normalizers are always applied to both the expected and actual sides of a
+// comparison
+type Normalizer func(text string) (normalized string)
+
+// Normalize returns the text after applying each of the normalizers in order
+func Normalize(text string, normalizers ...Normalizer) (normalized string) {
+	normalized = text
+	for _, normalizer := range normalizers {
+		normalized = normalizer(normalized)
+	}
+	return
+}
+
+// ReplaceRoots returns a Normalizer which replaces the paths of the given
+// TData instances with a placeholder: TestDataPlaceholder for TestData,
+// TempDataPlaceholder for TempData and TDataPlaceholder for anything else.
+// Longer paths are replaced first so that nested instances are scrubbed
+// correctly and where a path involves symlinks (such as the macOS temporary
+// directory), the resolved path is also replaced
+func ReplaceRoots(tds ...TData) Normalizer {
+	type replacement struct {
+		path, placeholder string
+	}
+	var replacements []replacement
+	for _, td := range tds {
+		placeholder := TDataPlaceholder
+		switch td.(type) {
+		case TestData:
+			placeholder = TestDataPlaceholder
+		case TempData:
+			placeholder = TempDataPlaceholder
+		}
+		replacements = append(replacements, replacement{path: td.Path(), placeholder: placeholder})
+		if resolved, err := filepath.EvalSymlinks(td.Path()); err == nil && resolved != td.Path() {
+			replacements = append(replacements, replacement{path: resolved, placeholder: placeholder})
+		}
+	}
+	sort.SliceStable(replacements, func(i, j int) bool {
+		return len(replacements[i].path) > len(replacements[j].path)
+	})
+	return func(text string) (normalized string) {
+		normalized = text
+		for _, r := range replacements {
+			normalized = strings.ReplaceAll(normalized, r.path, r.placeholder)
+		}
+		return
+	}
+}
+
+// Redact returns a Normalizer which replaces all matches of the regular
+// expression pattern with the replacement given, which may use the `$1`
+// style expansions of regexp.Regexp.ReplaceAllString. Redact panics if the
+// pattern does not compile
+func Redact(pattern, replacement string) Normalizer {
+	rx := regexp.MustCompile(pattern)
+	return func(text string) (normalized string) {
+		return rx.ReplaceAllString(text, replacement)
+	}
+}
+
+var (
+	rxUUID      = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	rxTimestamp = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?\b`)
+)
+
+// RedactUUIDs is a Normalizer which replaces all UUIDs with `<UUID>`
+func RedactUUIDs(text string) (normalized string) {
+	return rxUUID.ReplaceAllString(text, "<UUID>")
+}
+
+// RedactTimestamps is a Normalizer which replaces all RFC 3339 style
+// timestamps with `<TIMESTAMP>`
+func RedactTimestamps(text string) (normalized string) {
+	return rxTimestamp.ReplaceAllString(text, "<TIMESTAMP>")
+}
+
+// NormalizeLineEndings is a Normalizer which converts all `\r\n` and `\r`
+// line endings to `\n`
+func NormalizeLineEndings(text string) (normalized string) {
+	normalized = strings.ReplaceAll(text, "\r\n", "\n")
+	normalized = strings.ReplaceAll(normalized, "\r", "\n")
+	return
+}
+
+// TrimTrailingSpace is a Normalizer which removes all trailing whitespace
+// from each line
+func TrimTrailingSpace(text string) (normalized string) {
+	lines := strings.Split(text, "\n")
+	for idx := range lines {
+		lines[idx] = strings.TrimRight(lines[idx], " \t\r")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SortLines is a Normalizer which sorts the lines of text, for output where
+// the order of lines is not significant. A trailing newline is preserved
+func SortLines(text string) (normalized string) {
+	lines := splitLines(text)
+	for idx := range lines {
+		lines[idx] = strings.TrimSuffix(lines[idx], "\n")
+	}
+	sort.Strings(lines)
+	normalized = strings.Join(lines, "\n")
+	if strings.HasSuffix(text, "\n") {
+		normalized += "\n"
+	}
+	return
+}
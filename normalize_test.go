@@ -0,0 +1,97 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNormalize(t *testing.T) {
+
+	Convey("ReplaceRoots", t, func() {
+		td := New()
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		nested, err := NewTempData(tmpd.Path(), "nested.*")
+		So(err, ShouldBeNil)
+
+		normalizer := ReplaceRoots(td, tmpd)
+		So(normalizer(td.Join("file.txt")+" "+tmpd.Join("out.txt")), ShouldEqual, "$TESTDATA/file.txt $TEMPDATA/out.txt")
+
+		normalizer = ReplaceRoots(tmpd, nested)
+		So(normalizer(nested.Join("a")+"\n"+tmpd.Join("b")), ShouldEqual, "$TEMPDATA/a\n$TEMPDATA/b")
+	})
+
+	Convey("Redact", t, func() {
+		So(Redact(`id=(\d+)`, "id=<${1}>")("id=10 id=20"), ShouldEqual, "id=<10> id=<20>")
+		So(RedactUUIDs("req 0F8FAD5B-D9CB-469F-A165-70867728950E done"), ShouldEqual, "req <UUID> done")
+		So(RedactTimestamps("at 2024-06-14T10:11:12.345Z and 2024-06-14 10:11:12+02:00"), ShouldEqual, "at <TIMESTAMP> and <TIMESTAMP>")
+	})
+
+	Convey("Lines", t, func() {
+		So(NormalizeLineEndings("one\r\ntwo\rthree\n"), ShouldEqual, "one\ntwo\nthree\n")
+		So(TrimTrailingSpace("one  \ntwo\t\n"), ShouldEqual, "one\ntwo\n")
+		So(SortLines("c\na\nb\n"), ShouldEqual, "a\nb\nc\n")
+		So(SortLines("c\na"), ShouldEqual, "a\nc")
+		So(Normalize("b \r\na \r\n", NormalizeLineEndings, TrimTrailingSpace, SortLines), ShouldEqual, "a\nb\n")
+	})
+
+	Convey("Golden", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		option := WithNormalizers(ReplaceRoots(tmpd), NormalizeLineEndings)
+		mt := newMockT("TestNormalize")
+		withUpdating(func() {
+			Golden(mt, tmpd, "paths.golden", "wrote "+tmpd.Join("out.txt")+"\r\n", option)
+		})
+		So(mt.failed(), ShouldEqual, "")
+		So(tmpd.F("paths.golden"), ShouldEqual, "wrote $TEMPDATA/out.txt\n")
+
+		// the golden side is normalized too
+		So(os.WriteFile(tmpd.Join("paths.golden"), []byte("wrote $TEMPDATA/out.txt\r\n"), 0644), ShouldBeNil)
+		mt = newMockT("TestNormalize")
+		Golden(mt, tmpd, "paths.golden", "wrote "+tmpd.Join("out.txt")+"\n", option)
+		So(mt.failed(), ShouldEqual, "")
+	})
+
+	Convey("CompareTrees", t, func() {
+		got, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer got.Destroy()
+		want, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer want.Destroy()
+
+		So(Tree{"log.txt": File("b  \r\na\r\n")}.Apply(got), ShouldBeNil)
+		So(Tree{"log.txt": File("a\nb\n")}.Apply(want), ShouldBeNil)
+
+		report, err := CompareTrees(got, want, nil)
+		So(err, ShouldBeNil)
+		So(report.ContentChanged, ShouldEqual, []string{"log.txt"})
+
+		report, err = CompareTrees(got, want, &CompareOptions{
+			Normalizers: []Normalizer{NormalizeLineEndings, TrimTrailingSpace, SortLines},
+		})
+		So(err, ShouldBeNil)
+		So(report.Equal(), ShouldBeTrue)
+	})
+
+}
@@ -0,0 +1,328 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	// PendingExtension is appended to the name of a golden file to give the
+	// name of its pending replacement, for example: `output.golden.new`
+	PendingExtension = ".new"
+	// ReviewFlag is the name of the command-line flag which enables review
+	// mode, where golden file mismatches write pending replacements
+	ReviewFlag = "tdata.review"
+	// ReviewEnv is the environment variable equivalent of the ReviewFlag
+	ReviewEnv = "TDATA_REVIEW"
+	// PendingIndexFile is the name of the file, within the top-level of a
+	// TData, which lists the slash-separated names of the pending files
+	// written by review mode for golden files which did not exist, one per
+	// line, so that they are still known to be pending by later processes
+	PendingIndexFile = ".tdata-pending"
+)
+
+var reviewGoldens = flag.Bool(ReviewFlag, false, "write mismatched tdata golden files as pending .new files for review")
+
+// Reviewing returns true if golden file mismatches write pending replacement
+// files for review, which is enabled with either the `-tdata.review` flag or
+// the TDATA_REVIEW environment variable set to a true value. Reviewing is
+// always false when Updating
+func Reviewing() (reviewing bool) {
+	if Updating() {
+		return false
+	} else if reviewing = *reviewGoldens; !reviewing {
+		reviewing, _ = strconv.ParseBool(os.Getenv(ReviewEnv))
+	}
+	return
+}
+
+var pendingWrites = struct {
+	sync.Mutex
+	// files maps the path of each pending file written to the test name
+	files map[string]string
+}{
+	files: make(map[string]string),
+}
+
+// reviewPending writes got as the pending replacement of the named golden
+// file, reporting the outcome to t
+func reviewPending(t testing.TB, td TData, name string, got []byte) {
+	t.Helper()
+	pendingWrites.Lock()
+	defer pendingWrites.Unlock()
+	if err := writeGoldenAs(td, name, name+PendingExtension, got); err != nil {
+		t.Errorf("error writing pending golden file %q: %v", name+PendingExtension, err)
+		return
+	}
+	if _, err := os.Stat(filepath.Join(td.Path(), filepath.FromSlash(name))); errors.Is(err, fs.ErrNotExist) {
+		if err = updatePendingIndex(td.Path(), []string{name}, nil); err != nil {
+			t.Errorf("error writing %s: %v", PendingIndexFile, err)
+		}
+	}
+	pendingWrites.files[td.Join(name+PendingExtension)] = t.Name()
+	t.Logf("pending golden file written: %s", name+PendingExtension)
+}
+
+// discardPending removes any stale pending replacement of the named golden
+// file, which now matches
+func discardPending(td TData, name string) {
+	pendingWrites.Lock()
+	defer pendingWrites.Unlock()
+	filename := td.Join(name + PendingExtension)
+	_ = os.Remove(filename)
+	delete(pendingWrites.files, filename)
+	_ = updatePendingIndex(td.Path(), nil, []string{name})
+}
+
+// readPendingIndex returns the golden file names listed in the
+// PendingIndexFile of the root given, an index which does not exist is empty
+func readPendingIndex(root string) (names map[string]bool, err error) {
+	names = make(map[string]bool)
+	var data []byte
+	if data, err = os.ReadFile(filepath.Join(root, PendingIndexFile)); errors.Is(err, fs.ErrNotExist) {
+		err = nil
+		return
+	} else if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			names[strings.TrimSuffix(line, PendingExtension)] = true
+		}
+	}
+	return
+}
+
+// updatePendingIndex adds and removes the golden file names given to and
+// from the PendingIndexFile of the root given, along with any names whose
+// pending files no longer exist, and removes the index once it is empty.
+// Callers hold the pendingWrites lock
+func updatePendingIndex(root string, add, remove []string) (err error) {
+	var names map[string]bool
+	if names, err = readPendingIndex(root); err != nil {
+		return
+	} else if len(names) == 0 && len(add) == 0 {
+		return
+	}
+	for _, name := range add {
+		names[name] = true
+	}
+	for _, name := range remove {
+		delete(names, name)
+	}
+	var lines []string
+	for name := range names {
+		if _, ee := os.Stat(filepath.Join(root, filepath.FromSlash(name)) + PendingExtension); ee == nil {
+			lines = append(lines, name+PendingExtension+"\n")
+		}
+	}
+	filename := filepath.Join(root, PendingIndexFile)
+	if len(lines) == 0 {
+		if err = os.Remove(filename); errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+	sort.Strings(lines)
+	return writeFileAtomic(filename, []byte(strings.Join(lines, "")), 0644)
+}
+
+// writePendingSummary prints the pending files written during the tests to w,
+// returning the number of files
+func writePendingSummary(w io.Writer) (count int) {
+	pendingWrites.Lock()
+	defer pendingWrites.Unlock()
+	if count = len(pendingWrites.files); count == 0 {
+		return
+	}
+	paths := make([]string, 0, count)
+	for path := range pendingWrites.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	cwd, _ := os.Getwd()
+	_, _ = fmt.Fprintf(w, "tdata: %d golden files have pending changes to review:\n", count)
+	for _, path := range paths {
+		display := path
+		if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			display = rel
+		}
+		_, _ = fmt.Fprintf(w, "  %s (%s)\n", display, pendingWrites.files[path])
+	}
+	_, _ = fmt.Fprintf(w, "tdata: accept with `tdata accept [names...]` or reject with `tdata reject [names...]`\n")
+	return
+}
+
+// isPending returns true if the file at the path given, which has the
+// PendingExtension and is within the root, is the pending replacement of a
+// golden file: either it was written by review mode within this process, it
+// is listed in the PendingIndexFile of the root or the golden file it
+// replaces exists next to it. Any other file with the PendingExtension is
+// left alone, it may well be a fixture in its own right
+func isPending(root, path string, index map[string]bool) (pending bool) {
+	pendingWrites.Lock()
+	_, pending = pendingWrites.files[path]
+	pendingWrites.Unlock()
+	golden := strings.TrimSuffix(path, PendingExtension)
+	if !pending {
+		rel, _ := filepath.Rel(root, golden)
+		pending = index[filepath.ToSlash(rel)]
+	}
+	if !pending {
+		_, err := os.Stat(golden)
+		pending = err == nil
+	}
+	return
+}
+
+// PendingFiles returns the slash-separated names of the golden files within
+// td which have a pending replacement, in lexical order. Pending replacements
+// of golden files which do not exist yet are included when written by review
+// mode, which lists them in the PendingIndexFile, other files with the
+// PendingExtension must be named explicitly to be accepted or rejected
+func PendingFiles(td TData) (names []string, err error) {
+	root := td.Path()
+	var index map[string]bool
+	if index, err = readPendingIndex(root); err != nil {
+		return
+	}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if d.Type().IsRegular() && strings.HasSuffix(d.Name(), PendingExtension) && d.Name() != PendingExtension && isPending(root, path, index) {
+			rel, _ := filepath.Rel(root, path)
+			names = append(names, strings.TrimSuffix(filepath.ToSlash(rel), PendingExtension))
+		}
+		return nil
+	})
+	return
+}
+
+// AcceptPending replaces each of the named golden files within td with its
+// pending replacement, all of the PendingFiles when no names are given. The
+// names may be given with or without the PendingExtension. The names of the
+// golden files replaced are returned and their entries, along with those of
+// the pending files, in any ChecksumsFile are refreshed
+func AcceptPending(td TData, names ...string) (accepted []string, err error) {
+	if names, err = pendingNames(td, names); err != nil {
+		return
+	}
+	defer func() {
+		err = errors.Join(err, resolvePending(td, accepted, true))
+	}()
+	for _, name := range names {
+		golden := filepath.Join(td.Path(), filepath.FromSlash(name))
+		if err = os.Rename(golden+PendingExtension, golden); err != nil {
+			return
+		}
+		accepted = append(accepted, name)
+	}
+	return
+}
+
+// RejectPending removes the pending replacement of each of the named golden
+// files within td, all of the PendingFiles when no names are given. The names
+// may be given with or without the PendingExtension. The names of the golden
+// files whose replacements were removed are returned
+func RejectPending(td TData, names ...string) (rejected []string, err error) {
+	if names, err = pendingNames(td, names); err != nil {
+		return
+	}
+	defer func() {
+		err = errors.Join(err, resolvePending(td, rejected, false))
+	}()
+	for _, name := range names {
+		if err = os.Remove(filepath.Join(td.Path(), filepath.FromSlash(name)) + PendingExtension); err != nil {
+			return
+		}
+		rejected = append(rejected, name)
+	}
+	return
+}
+
+// resolvePending removes the named golden files from the PendingIndexFile of
+// td and refreshes the entries in any ChecksumsFile of td for their pending
+// files and, when accepted, for the golden files themselves
+func resolvePending(td TData, names []string, accepted bool) (err error) {
+	pendingWrites.Lock()
+	err = updatePendingIndex(td.Path(), nil, names)
+	pendingWrites.Unlock()
+	var paths []string
+	for _, name := range names {
+		if accepted {
+			paths = append(paths, name)
+		}
+		paths = append(paths, name+PendingExtension)
+	}
+	return errors.Join(err, refreshChecksums(td, paths...))
+}
+
+// PendingDiff returns the differences between the named golden file within
+// td and its pending replacement, as a UnifiedDiff for text and a HexDiff for
+// binary contents. A missing golden file is the same as an empty one
+func PendingDiff(td TData, name string) (diff string, err error) {
+	name = strings.TrimSuffix(filepath.ToSlash(name), PendingExtension)
+	filename := filepath.Join(td.Path(), filepath.FromSlash(name))
+	var want, got []byte
+	if got, err = os.ReadFile(filename + PendingExtension); err != nil {
+		return
+	} else if got, err = decodeGolden(name, got); err != nil {
+		return
+	}
+	if want, err = os.ReadFile(filename); errors.Is(err, fs.ErrNotExist) {
+		want, err = nil, nil
+	} else if err != nil {
+		return
+	} else if want, err = decodeGolden(name, want); err != nil {
+		return
+	}
+	if isText(want) && isText(got) {
+		diff = UnifiedDiff(string(want), string(got), DiffOptions{LabelA: name, LabelB: name + PendingExtension})
+	} else {
+		diff = HexDiff(want, got)
+	}
+	return
+}
+
+// pendingNames returns the golden file names given, without the
+// PendingExtension, or all of the PendingFiles when none are given
+func pendingNames(td TData, given []string) (names []string, err error) {
+	if len(given) == 0 {
+		return PendingFiles(td)
+	}
+	for _, name := range given {
+		name = strings.TrimSuffix(filepath.ToSlash(name), PendingExtension)
+		pending := filepath.Join(td.Path(), filepath.FromSlash(name)) + PendingExtension
+		if _, err = os.Stat(pending); err != nil {
+			err = fmt.Errorf("%w: %s", fs.ErrNotExist, name+PendingExtension)
+			return
+		}
+		names = append(names, name)
+	}
+	return
+}
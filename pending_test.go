@@ -0,0 +1,289 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// withReviewing runs fn with review mode enabled and no pending files
+// recorded, restoring the previous state afterwards
+func withReviewing(fn func()) {
+	pendingWrites.Lock()
+	files := pendingWrites.files
+	pendingWrites.files = make(map[string]string)
+	pendingWrites.Unlock()
+	*reviewGoldens = true
+	defer func() {
+		*reviewGoldens = false
+		pendingWrites.Lock()
+		pendingWrites.files = files
+		pendingWrites.Unlock()
+	}()
+	fn()
+}
+
+func TestPending(t *testing.T) {
+
+	Convey("Reviewing", t, func() {
+		So(Reviewing(), ShouldBeFalse)
+		t.Setenv(ReviewEnv, "true")
+		So(Reviewing(), ShouldBeTrue)
+		withUpdating(func() {
+			So(Reviewing(), ShouldBeFalse)
+		})
+		t.Setenv(ReviewEnv, "")
+		withReviewing(func() {
+			So(Reviewing(), ShouldBeTrue)
+		})
+	})
+
+	Convey("Review Mode", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"match.golden":         File("same\n"),
+			"match.golden.new":     File("stale\n"),
+			"mismatch.golden":      File("old\n"),
+			"binary.golden":        File("\x00\x01"),
+			"compressed.golden.gz": File(""),
+		}.Apply(tmpd), ShouldBeNil)
+		So(writeGolden(tmpd, "compressed.golden.gz", []byte("old\n")), ShouldBeNil)
+
+		var summary strings.Builder
+		withReviewing(func() {
+			match := newMockT("TestMatch")
+			Golden(match, tmpd, "match.golden", "same\n")
+			So(match.failed(), ShouldEqual, "")
+			So(tmpd.E("match.golden.new"), ShouldBeFalse)
+
+			mismatch := newMockT("TestMismatch")
+			Golden(mismatch, tmpd, "mismatch.golden", "new\n")
+			So(mismatch.failed(), ShouldStartWith, "golden file \"mismatch.golden\" mismatch:\n")
+			So(mismatch.logs, ShouldEqual, []string{"pending golden file written: mismatch.golden.new"})
+			So(tmpd.F("mismatch.golden"), ShouldEqual, "old\n")
+			So(tmpd.F("mismatch.golden.new"), ShouldEqual, "new\n")
+
+			missing := newMockT("TestMissing")
+			Golden(missing, tmpd, "nested/missing.golden", "created\n")
+			So(missing.failed(), ShouldContainSubstring, "not found")
+			So(tmpd.F("nested/missing.golden.new"), ShouldEqual, "created\n")
+
+			compressed := newMockT("TestCompressed")
+			Golden(compressed, tmpd, "compressed.golden.gz", "new\n")
+			data, err := os.ReadFile(tmpd.Join("compressed.golden.gz.new"))
+			So(err, ShouldBeNil)
+			data, err = decodeGolden("compressed.golden.gz", data)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "new\n")
+
+			GoldenBytes(newMockT("TestBinary"), tmpd, "binary.golden", []byte{0, 2})
+
+			// concurrent subtests
+			var wg sync.WaitGroup
+			for idx := range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					Golden(newMockT(fmt.Sprintf("TestParallel/%d", idx)), tmpd, fmt.Sprintf("parallel/%d.golden", idx), "parallel\n")
+				}()
+			}
+			wg.Wait()
+
+			So(writePendingSummary(&summary), ShouldEqual, 12)
+		})
+		So(summary.String(), ShouldStartWith, "tdata: 12 golden files have pending changes to review:\n")
+		So(summary.String(), ShouldContainSubstring, "  "+tmpd.Join("mismatch.golden.new")+" (TestMismatch)\n")
+		So(summary.String(), ShouldContainSubstring, "  "+tmpd.Join("parallel", "7.golden.new")+" (TestParallel/7)\n")
+		So(summary.String(), ShouldEndWith, "tdata: accept with `tdata accept [names...]` or reject with `tdata reject [names...]`\n")
+
+		var empty strings.Builder
+		withReviewing(func() {
+			So(writePendingSummary(&empty), ShouldEqual, 0)
+		})
+		So(empty.String(), ShouldEqual, "")
+
+		// the replacements of missing golden files are listed in the index
+		So(tmpd.F(PendingIndexFile), ShouldStartWith, "nested/missing.golden.new\nparallel/0.golden.new\n")
+		pendingWrites.Lock()
+		clear(pendingWrites.files)
+		pendingWrites.Unlock()
+		names, err := PendingFiles(tmpd)
+		So(err, ShouldBeNil)
+		So(names, ShouldEqual, []string{
+			"binary.golden", "compressed.golden.gz", "mismatch.golden", "nested/missing.golden",
+			"parallel/0.golden", "parallel/1.golden", "parallel/2.golden", "parallel/3.golden",
+			"parallel/4.golden", "parallel/5.golden", "parallel/6.golden", "parallel/7.golden",
+		})
+
+		diff, err := PendingDiff(tmpd, "mismatch.golden")
+		So(err, ShouldBeNil)
+		So(diff, ShouldEqual, ""+
+			"--- mismatch.golden\n"+
+			"+++ mismatch.golden.new\n"+
+			"@@ -1 +1 @@\n"+
+			"- 1   | old\n"+
+			"+   1 | new\n",
+		)
+		diff, err = PendingDiff(tmpd, "compressed.golden.gz.new")
+		So(err, ShouldBeNil)
+		So(diff, ShouldContainSubstring, "+   1 | new\n")
+		diff, err = PendingDiff(tmpd, "nested/missing.golden")
+		So(err, ShouldBeNil)
+		So(diff, ShouldContainSubstring, "+   1 | created\n")
+		diff, err = PendingDiff(tmpd, "binary.golden")
+		So(err, ShouldBeNil)
+		So(diff, ShouldEqual, HexDiff([]byte{0, 1}, []byte{0, 2}))
+		_, err = PendingDiff(tmpd, "match.golden")
+		So(errors.Is(err, fs.ErrNotExist), ShouldBeTrue)
+
+		rejected, err := RejectPending(tmpd, "binary.golden.new")
+		So(err, ShouldBeNil)
+		So(rejected, ShouldEqual, []string{"binary.golden"})
+		So(tmpd.E("binary.golden.new"), ShouldBeFalse)
+		So(tmpd.F("binary.golden"), ShouldEqual, "\x00\x01")
+		_, err = RejectPending(tmpd, "binary.golden")
+		So(errors.Is(err, fs.ErrNotExist), ShouldBeTrue)
+
+		rejected, err = RejectPending(tmpd, "nested/missing.golden.new")
+		So(err, ShouldBeNil)
+		So(rejected, ShouldEqual, []string{"nested/missing.golden"})
+		So(tmpd.F(PendingIndexFile), ShouldStartWith, "parallel/0.golden.new\n")
+
+		accepted, err := AcceptPending(tmpd, "parallel/0.golden")
+		So(err, ShouldBeNil)
+		So(accepted, ShouldEqual, []string{"parallel/0.golden"})
+		So(tmpd.F("parallel/0.golden"), ShouldEqual, "parallel\n")
+
+		rejected, err = RejectPending(tmpd)
+		So(err, ShouldBeNil)
+		So(rejected, ShouldHaveLength, 9)
+		So(rejected[:2], ShouldEqual, []string{"compressed.golden.gz", "mismatch.golden"})
+		names, err = PendingFiles(tmpd)
+		So(err, ShouldBeNil)
+		So(names, ShouldBeEmpty)
+		So(tmpd.E("parallel/7.golden.new"), ShouldBeFalse)
+		So(tmpd.E(PendingIndexFile), ShouldBeFalse)
+	})
+
+	Convey("Accept", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"a.golden":            File("a\n"),
+			"a.golden.new":        File("new a\n"),
+			"nested/b.golden":     File("b\n"),
+			"nested/b.golden.new": File("new b\n"),
+			"nested/c.golden.new": File("new c\n"),
+			"nested/d.golden.new": File("new d\n"),
+			PendingIndexFile:      File("nested/c.golden.new\n"),
+			"unrelated.txt":       File("unrelated\n"),
+			"data.new":            File("fixture\n"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		names, err := PendingFiles(tmpd)
+		So(err, ShouldBeNil)
+		So(names, ShouldEqual, []string{"a.golden", "nested/b.golden", "nested/c.golden"})
+
+		accepted, err := AcceptPending(tmpd, "nested/b.golden.new")
+		So(err, ShouldBeNil)
+		So(accepted, ShouldEqual, []string{"nested/b.golden"})
+		So(tmpd.F("nested/b.golden"), ShouldEqual, "new b\n")
+		So(tmpd.E("nested/b.golden.new"), ShouldBeFalse)
+
+		_, err = AcceptPending(tmpd, "unrelated.txt")
+		So(errors.Is(err, fs.ErrNotExist), ShouldBeTrue)
+		So(err.Error(), ShouldEqual, "file does not exist: unrelated.txt.new")
+
+		accepted, err = AcceptPending(tmpd)
+		So(err, ShouldBeNil)
+		So(accepted, ShouldEqual, []string{"a.golden", "nested/c.golden"})
+		So(tmpd.F("a.golden"), ShouldEqual, "new a\n")
+		So(tmpd.F("nested/c.golden"), ShouldEqual, "new c\n")
+		So(tmpd.E(PendingIndexFile), ShouldBeFalse)
+		So(tmpd.E("nested/d.golden"), ShouldBeFalse)
+
+		// new golden files not in the index are accepted by name
+		accepted, err = AcceptPending(tmpd, "nested/d.golden")
+		So(err, ShouldBeNil)
+		So(accepted, ShouldEqual, []string{"nested/d.golden"})
+		So(tmpd.F("nested/d.golden"), ShouldEqual, "new d\n")
+
+		names, err = PendingFiles(tmpd)
+		So(err, ShouldBeNil)
+		So(names, ShouldBeEmpty)
+
+		// a fixture which happens to have the PendingExtension is left alone
+		rejected, err := RejectPending(tmpd)
+		So(err, ShouldBeNil)
+		So(rejected, ShouldBeEmpty)
+		So(tmpd.F("data.new"), ShouldEqual, "fixture\n")
+		So(tmpd.E("data"), ShouldBeFalse)
+	})
+
+	Convey("Review Snapshots and HTTP Fixtures", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"__snapshots__/TestSame.snap":     File("--- snapshot 1 ---\n\"same\"\n"),
+			"__snapshots__/TestSame.snap.new": File("stale\n"),
+			"__snapshots__/TestOther.snap":    File("--- snapshot 1 ---\n\"old\"\n"),
+			"hello.http":                      File("GET /hello?name=world\n###\nHTTP/1.1 200 OK\n\nhello there\n"),
+			"match.http":                      File("GET /hello?name=world\n###\nHTTP/1.1 200 OK\n\nhello world\n"),
+			"match.http.new":                  File("stale\n"),
+		}.Apply(tmpd), ShouldBeNil)
+		handler := newTestHandler()
+
+		withReviewing(func() {
+			same := newMockT("TestSame")
+			SnapshotIn(same, tmpd, "same")
+			same.runCleanup()
+			So(same.failed(), ShouldEqual, "")
+			So(tmpd.E("__snapshots__/TestSame.snap.new"), ShouldBeFalse)
+
+			other := newMockT("TestOther")
+			SnapshotIn(other, tmpd, "new")
+			SnapshotIn(other, tmpd, "added")
+			other.runCleanup()
+			So(other.failed(), ShouldContainSubstring, "snapshot \"__snapshots__/TestOther.snap\" #1 mismatch:\n")
+			So(other.logs, ShouldEqual, []string{"pending golden file written: __snapshots__/TestOther.snap.new"})
+			So(tmpd.F("__snapshots__/TestOther.snap"), ShouldEqual, "--- snapshot 1 ---\n\"old\"\n")
+			So(tmpd.F("__snapshots__/TestOther.snap.new"), ShouldEqual, "--- snapshot 1 ---\n\"new\"\n--- snapshot 2 ---\n\"added\"\n")
+
+			hello := newMockT("TestHello")
+			GoldenHTTP(hello, tmpd, "hello.http", handler, nil)
+			So(hello.failed(), ShouldStartWith, "http fixture \"hello.http\" mismatch:\n")
+			So(hello.logs, ShouldEqual, []string{"pending golden file written: hello.http.new"})
+			So(tmpd.F("hello.http.new"), ShouldEqual, "GET /hello?name=world\n###\nHTTP/1.1 200 OK\nContent-Type: text/plain\nDate: *\n\nhello world\n")
+
+			match := newMockT("TestMatch")
+			GoldenHTTP(match, tmpd, "match.http", handler, nil)
+			So(match.failed(), ShouldEqual, "")
+			So(tmpd.E("match.http.new"), ShouldBeFalse)
+		})
+	})
+
+}
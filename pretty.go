@@ -0,0 +1,238 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Pretty returns a deterministic, multi-line representation of any Go value.
+// Map entries are sorted, pointers are printed as the value they point to
+// (never as an address), recursive pointers are printed as `<cycle>`,
+// time.Time values use RFC 3339 and errors use their Error text. Unexported
+// struct fields are included, their time.Time values are read from the
+// fields of the time.Time as their methods cannot be called, while their
+// errors are printed as the values of the error types for the same reason
+func Pretty(value any) (text string) {
+	p := &prettyPrinter{visited: make(map[uintptr]struct{})}
+	p.print(reflect.ValueOf(value), 0)
+	return p.buf.String()
+}
+
+type prettyPrinter struct {
+	buf     strings.Builder
+	visited map[uintptr]struct{}
+}
+
+func (p *prettyPrinter) indent(depth int) {
+	p.buf.WriteString(strings.Repeat("  ", depth))
+}
+
+func (p *prettyPrinter) print(v reflect.Value, depth int) {
+	if !v.IsValid() {
+		p.buf.WriteString("nil")
+		return
+	}
+
+	if v.Type() == timeType {
+		if v.CanInterface() {
+			p.buf.WriteString("time.Time(" + v.Interface().(time.Time).Format(time.RFC3339Nano) + ")")
+			return
+		} else if t, ok := prettyTime(v); ok {
+			p.buf.WriteString("time.Time(" + t.Format(time.RFC3339Nano) + ")")
+			return
+		}
+	}
+	if v.Kind() != reflect.Interface && v.Type().Implements(errorType) && v.CanInterface() {
+		if v.Kind() != reflect.Pointer || !v.IsNil() {
+			p.buf.WriteString(v.Type().String() + "(" + strconv.Quote(v.Interface().(error).Error()) + ")")
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		p.buf.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		p.typed(v, strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		p.typed(v, strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		p.typed(v, strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()))
+	case reflect.Complex64, reflect.Complex128:
+		p.typed(v, strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits()))
+	case reflect.String:
+		p.typed(v, strconv.Quote(v.String()))
+
+	case reflect.Pointer:
+		if v.IsNil() {
+			p.buf.WriteString("(" + v.Type().String() + ")(nil)")
+			return
+		}
+		ptr := v.Pointer()
+		if _, present := p.visited[ptr]; present {
+			p.buf.WriteString("&<cycle>")
+			return
+		}
+		p.visited[ptr] = struct{}{}
+		p.buf.WriteString("&")
+		p.print(v.Elem(), depth)
+		delete(p.visited, ptr)
+
+	case reflect.Interface:
+		p.print(v.Elem(), depth)
+
+	case reflect.Struct:
+		p.buf.WriteString(v.Type().String() + "{")
+		if v.NumField() == 0 {
+			p.buf.WriteString("}")
+			return
+		}
+		p.buf.WriteString("\n")
+		for idx := 0; idx < v.NumField(); idx++ {
+			p.indent(depth + 1)
+			p.buf.WriteString(v.Type().Field(idx).Name + ": ")
+			p.print(v.Field(idx), depth+1)
+			p.buf.WriteString(",\n")
+		}
+		p.indent(depth)
+		p.buf.WriteString("}")
+
+	case reflect.Slice:
+		if v.IsNil() {
+			p.buf.WriteString(v.Type().String() + "(nil)")
+			return
+		} else if v.Type().Elem().Kind() == reflect.Uint8 {
+			p.buf.WriteString(v.Type().String() + "(" + strconv.Quote(string(v.Bytes())) + ")")
+			return
+		}
+		p.list(v, depth)
+
+	case reflect.Array:
+		p.list(v, depth)
+
+	case reflect.Map:
+		if v.IsNil() {
+			p.buf.WriteString(v.Type().String() + "(nil)")
+			return
+		}
+		type entry struct {
+			key, value string
+		}
+		var entries []entry
+		iter := v.MapRange()
+		for iter.Next() {
+			kp := &prettyPrinter{visited: p.visited}
+			kp.print(iter.Key(), depth+1)
+			vp := &prettyPrinter{visited: p.visited}
+			vp.print(iter.Value(), depth+1)
+			entries = append(entries, entry{key: kp.buf.String(), value: vp.buf.String()})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].key < entries[j].key
+		})
+		p.buf.WriteString(v.Type().String() + "{")
+		if len(entries) == 0 {
+			p.buf.WriteString("}")
+			return
+		}
+		p.buf.WriteString("\n")
+		for _, e := range entries {
+			p.indent(depth + 1)
+			p.buf.WriteString(e.key + ": " + e.value + ",\n")
+		}
+		p.indent(depth)
+		p.buf.WriteString("}")
+
+	default:
+		// funcs, channels and unsafe pointers have no stable representation
+		if v.IsNil() {
+			p.buf.WriteString("(" + v.Type().String() + ")(nil)")
+		} else {
+			p.buf.WriteString("(" + v.Type().String() + ")(<non-nil>)")
+		}
+	}
+}
+
+// prettyTime returns the time.Time of a value obtained through an unexported
+// struct field, which cannot use Interface, from the wall, ext and loc fields
+// of the time.Time, see the time package for their encoding
+func prettyTime(v reflect.Value) (t time.Time, ok bool) {
+	const (
+		hasMonotonic         = 1 << 63
+		nsecMask             = 1<<30 - 1
+		nsecShift            = 30
+		secondsPerDay        = 86400
+		wallToInternal int64 = (1884*365 + 1884/4 - 1884/100 + 1884/400) * secondsPerDay
+		unixToInternal int64 = (1969*365 + 1969/4 - 1969/100 + 1969/400) * secondsPerDay
+	)
+	wall, ext, loc := v.FieldByName("wall"), v.FieldByName("ext"), v.FieldByName("loc")
+	if wall.Kind() != reflect.Uint64 || ext.Kind() != reflect.Int64 || loc.Kind() != reflect.Pointer {
+		return
+	}
+	w, sec := wall.Uint(), ext.Int()
+	if w&hasMonotonic != 0 {
+		sec = wallToInternal + int64(w<<1>>(nsecShift+1))
+	}
+	t, ok = time.Unix(sec-unixToInternal, int64(w&nsecMask)).UTC(), true
+	if loc.IsNil() {
+		return
+	}
+	name := loc.Elem().FieldByName("name")
+	if name.Kind() != reflect.String || name.String() == "" || name.String() == "UTC" {
+		return
+	} else if location, err := time.LoadLocation(name.String()); err == nil {
+		t = t.In(location)
+	} else if zones := loc.Elem().FieldByName("zone"); zones.Kind() == reflect.Slice && zones.Len() == 1 {
+		// such as a time.FixedZone
+		if offset := zones.Index(0).FieldByName("offset"); offset.Kind() == reflect.Int {
+			t = t.In(time.FixedZone(name.String(), int(offset.Int())))
+		}
+	}
+	return
+}
+
+// typed writes the text, wrapped in the type name for named types
+func (p *prettyPrinter) typed(v reflect.Value, text string) {
+	if v.Type().PkgPath() != "" {
+		text = v.Type().String() + "(" + text + ")"
+	}
+	p.buf.WriteString(text)
+}
+
+func (p *prettyPrinter) list(v reflect.Value, depth int) {
+	p.buf.WriteString(v.Type().String() + "{")
+	if v.Len() == 0 {
+		p.buf.WriteString("}")
+		return
+	}
+	p.buf.WriteString("\n")
+	for idx := 0; idx < v.Len(); idx++ {
+		p.indent(depth + 1)
+		p.print(v.Index(idx), depth+1)
+		p.buf.WriteString(",\n")
+	}
+	p.indent(depth)
+	p.buf.WriteString("}")
+}
@@ -0,0 +1,694 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const (
+	// ScreenTabWidth is the distance between the tab stops of a Screen
+	ScreenTabWidth = 8
+	// ScreenAttributesHeader separates the text and attribute layers of a
+	// rendered Screen
+	ScreenAttributesHeader = "--- attributes ---"
+)
+
+// screenAttrKeys are the characters used to identify each distinct Attr in
+// the attribute layer of a rendered Screen, cells with the default Attr are
+// shown as a '.' and any Attr beyond the available keys as a '?'
+const screenAttrKeys = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// AttrFlags is a bitmask of the boolean text attributes of a Cell
+type AttrFlags uint16
+
+const (
+	AttrBold AttrFlags = 1 << iota
+	AttrDim
+	AttrItalic
+	AttrUnderline
+	AttrBlink
+	AttrReverse
+	AttrHidden
+	AttrStrike
+)
+
+var attrFlagNames = []string{"bold", "dim", "italic", "underline", "blink", "reverse", "hidden", "strike"}
+
+// sgrFlags maps the SGR parameters which set an AttrFlags to the flag set
+var sgrFlags = map[int]AttrFlags{
+	1: AttrBold, 2: AttrDim, 3: AttrItalic, 4: AttrUnderline, 5: AttrBlink,
+	6: AttrBlink, 7: AttrReverse, 8: AttrHidden, 9: AttrStrike, 21: AttrUnderline,
+}
+
+// Color is a terminal color, the zero value is the terminal's default color
+type Color uint32
+
+const (
+	colorIndexed Color = 1 << 24
+	colorRGB     Color = 1 << 25
+)
+
+// IndexedColor returns the Color for the given 256-color palette index, the
+// first sixteen of which are the standard and bright ANSI colors
+func IndexedColor(index uint8) Color {
+	return colorIndexed | Color(index)
+}
+
+// RGBColor returns the 24-bit Color for the given red, green and blue
+func RGBColor(r, g, b uint8) Color {
+	return colorRGB | Color(r)<<16 | Color(g)<<8 | Color(b)
+}
+
+// String returns "default", the palette index or the "#rrggbb" hex value
+func (c Color) String() (name string) {
+	switch {
+	case c&colorRGB != 0:
+		return fmt.Sprintf("#%06x", uint32(c&0xffffff))
+	case c&colorIndexed != 0:
+		return strconv.Itoa(int(c & 0xff))
+	}
+	return "default"
+}
+
+// Attr describes how the text of a Cell is displayed, the zero value is the
+// terminal's default appearance
+type Attr struct {
+	Flags AttrFlags
+	FG    Color
+	BG    Color
+}
+
+// String returns a space separated description of the attributes, for
+// example: "bold underline fg=1 bg=#102030", or "default"
+func (a Attr) String() (description string) {
+	var parts []string
+	for idx, name := range attrFlagNames {
+		if a.Flags&(1<<idx) != 0 {
+			parts = append(parts, name)
+		}
+	}
+	if a.FG != 0 {
+		parts = append(parts, "fg="+a.FG.String())
+	}
+	if a.BG != 0 {
+		parts = append(parts, "bg="+a.BG.String())
+	}
+	if len(parts) == 0 {
+		return "default"
+	}
+	return strings.Join(parts, " ")
+}
+
+// Cell is a single character position of a Screen
+type Cell struct {
+	Rune rune
+	Attr Attr
+}
+
+const (
+	screenGround = iota
+	screenEscape
+	screenCharset
+	screenCSI
+	screenString
+	screenStringEscape
+)
+
+// Screen is a virtual terminal which consumes text and ANSI escape sequences
+// into a fixed size grid of Cells, so that the output of terminal user
+// interfaces can be compared by what is displayed instead of by the bytes
+// used to display it
+//
+// Screen supports cursor movement, erasing, inserting and deleting
+// characters and lines, scrolling regions, SGR attributes (including 256 and
+// 24-bit colors), saving and restoring the cursor and the alternate screen.
+// Other sequences, such as OSC titles and mode changes, are consumed and
+// ignored. Every rune occupies a single cell
+type Screen struct {
+	// AutoCR makes line feeds also return the cursor to the first column, as
+	// a terminal does when translating output newlines, useful for output
+	// written with "\n" instead of "\r\n"
+	AutoCR bool
+
+	width  int
+	height int
+	cells  [][]Cell
+	row    int
+	col    int
+	attr   Attr
+	wrap   bool
+	top    int
+	bottom int
+
+	saved     [2]int
+	savedAttr Attr
+	main      [][]Cell
+	mainSaved [2]int
+
+	state   int
+	params  []byte
+	partial []byte
+}
+
+// NewScreen returns a blank Screen of the given size, which must be at least
+// one column wide and one row high
+func NewScreen(width, height int) (s *Screen) {
+	s = &Screen{width: max(width, 1), height: max(height, 1)}
+	s.Reset()
+	return
+}
+
+// Reset clears the screen and restores the initial state
+func (s *Screen) Reset() {
+	s.attr = Attr{}
+	s.cells = make([][]Cell, s.height)
+	for idx := range s.cells {
+		s.cells[idx] = s.blankRow()
+	}
+	s.row, s.col, s.wrap = 0, 0, false
+	s.top, s.bottom = 0, s.height-1
+	s.saved, s.savedAttr = [2]int{}, Attr{}
+	s.main = nil
+	s.state, s.params, s.partial = screenGround, nil, nil
+}
+
+// Size returns the width and height of the screen
+func (s *Screen) Size() (width, height int) {
+	return s.width, s.height
+}
+
+// Cursor returns the current zero-based row and column of the cursor
+func (s *Screen) Cursor() (row, col int) {
+	return s.row, s.col
+}
+
+// Cell returns the Cell at the given zero-based row and column, positions
+// outside the screen return the zero Cell
+func (s *Screen) Cell(row, col int) (cell Cell) {
+	if row >= 0 && row < s.height && col >= 0 && col < s.width {
+		cell = s.cells[row][col]
+	}
+	return
+}
+
+// WriteString is a convenience wrapper around Write
+func (s *Screen) WriteString(text string) (n int, err error) {
+	return s.Write([]byte(text))
+}
+
+// Write consumes the given output, escape sequences and UTF-8 encoded runes
+// may be split across multiple calls to Write
+func (s *Screen) Write(p []byte) (n int, err error) {
+	for _, b := range p {
+		s.consume(b)
+	}
+	return len(p), nil
+}
+
+func (s *Screen) consume(b byte) {
+	switch s.state {
+
+	case screenEscape:
+		s.state = screenGround
+		switch b {
+		case '[':
+			s.state, s.params = screenCSI, s.params[:0]
+		case ']', 'P', 'X', '^', '_':
+			s.state = screenString
+		case '(', ')', '*', '+':
+			s.state = screenCharset
+		case '7':
+			s.saveCursor()
+		case '8':
+			s.restoreCursor()
+		case 'c':
+			s.Reset()
+		case 'D':
+			s.lineFeed()
+		case 'E':
+			s.col = 0
+			s.lineFeed()
+		case 'M':
+			s.reverseIndex()
+		case 0x1b:
+			s.state = screenEscape
+		}
+
+	case screenCharset:
+		s.state = screenGround
+
+	case screenCSI:
+		switch {
+		case b == 0x1b:
+			s.state = screenEscape
+		case b >= 0x20 && b <= 0x3f:
+			s.params = append(s.params, b)
+		case b >= 0x40 && b <= 0x7e:
+			s.state = screenGround
+			s.csi(b)
+		}
+
+	case screenString:
+		switch b {
+		case 0x07:
+			s.state = screenGround
+		case 0x1b:
+			s.state = screenStringEscape
+		}
+
+	case screenStringEscape:
+		if b == '\\' {
+			s.state = screenGround
+		} else {
+			s.state = screenString
+		}
+
+	default:
+		s.ground(b)
+	}
+}
+
+func (s *Screen) ground(b byte) {
+	if len(s.partial) > 0 || b >= 0x80 {
+		s.partial = append(s.partial, b)
+		if utf8.FullRune(s.partial) {
+			r, size := utf8.DecodeRune(s.partial)
+			s.partial = s.partial[size:]
+			s.put(r)
+			// an invalid sequence only consumes the leading byte
+			rest := s.partial
+			s.partial = nil
+			for _, c := range rest {
+				s.consume(c)
+			}
+		}
+		return
+	}
+
+	switch b {
+	case 0x1b:
+		s.state = screenEscape
+	case '\r':
+		s.col, s.wrap = 0, false
+	case '\n', '\v', '\f':
+		if s.AutoCR {
+			s.col = 0
+		}
+		s.lineFeed()
+	case '\b':
+		if s.col > 0 {
+			s.col--
+		}
+		s.wrap = false
+	case '\t':
+		s.col = min((s.col/ScreenTabWidth+1)*ScreenTabWidth, s.width-1)
+	default:
+		if b >= 0x20 && b != 0x7f {
+			s.put(rune(b))
+		}
+	}
+}
+
+func (s *Screen) put(r rune) {
+	if s.wrap {
+		s.col, s.wrap = 0, false
+		s.lineFeed()
+	}
+	s.cells[s.row][s.col] = Cell{Rune: r, Attr: s.attr}
+	if s.col == s.width-1 {
+		s.wrap = true
+	} else {
+		s.col++
+	}
+}
+
+func (s *Screen) blank() (cell Cell) {
+	return Cell{Rune: ' ', Attr: Attr{BG: s.attr.BG}}
+}
+
+func (s *Screen) blankRow() (row []Cell) {
+	row = make([]Cell, s.width)
+	for idx := range row {
+		row[idx] = s.blank()
+	}
+	return
+}
+
+func (s *Screen) lineFeed() {
+	s.wrap = false
+	if s.row == s.bottom {
+		s.scrollUp(1)
+	} else if s.row < s.height-1 {
+		s.row++
+	}
+}
+
+func (s *Screen) reverseIndex() {
+	s.wrap = false
+	if s.row == s.top {
+		s.scrollDown(1)
+	} else if s.row > 0 {
+		s.row--
+	}
+}
+
+// scrollUp removes n lines from the top of the scrolling region, adding blank
+// lines to the bottom
+func (s *Screen) scrollUp(n int) {
+	s.deleteLines(s.top, n)
+}
+
+// scrollDown adds n blank lines to the top of the scrolling region, removing
+// lines from the bottom
+func (s *Screen) scrollDown(n int) {
+	s.insertLines(s.top, n)
+}
+
+func (s *Screen) deleteLines(from, n int) {
+	n = min(n, s.bottom-from+1)
+	region := s.cells[from : s.bottom+1]
+	copy(region, region[n:])
+	for idx := len(region) - n; idx < len(region); idx++ {
+		region[idx] = s.blankRow()
+	}
+}
+
+func (s *Screen) insertLines(from, n int) {
+	n = min(n, s.bottom-from+1)
+	region := s.cells[from : s.bottom+1]
+	copy(region[n:], region)
+	for idx := 0; idx < n; idx++ {
+		region[idx] = s.blankRow()
+	}
+}
+
+func (s *Screen) erase(row, from, to int) {
+	for col := max(from, 0); col < min(to, s.width); col++ {
+		s.cells[row][col] = s.blank()
+	}
+}
+
+func (s *Screen) saveCursor() {
+	s.saved, s.savedAttr = [2]int{s.row, s.col}, s.attr
+}
+
+func (s *Screen) restoreCursor() {
+	s.row, s.col, s.attr, s.wrap = s.saved[0], s.saved[1], s.savedAttr, false
+}
+
+func (s *Screen) moveTo(row, col int) {
+	s.row = min(max(row, 0), s.height-1)
+	s.col = min(max(col, 0), s.width-1)
+	s.wrap = false
+}
+
+func (s *Screen) csi(final byte) {
+	text := string(s.params)
+	private := strings.HasPrefix(text, "?")
+	if private || strings.ContainsAny(text, "<=> !\"#$%&'()*+,-./") {
+		if private && (final == 'h' || final == 'l') {
+			for _, mode := range parseScreenParams(text[1:]) {
+				if mode == 1049 || mode == 1047 || mode == 47 {
+					s.alternate(final == 'h')
+				}
+			}
+		}
+		return
+	}
+
+	params := parseScreenParams(text)
+	param := func(idx, def int) (value int) {
+		if idx < len(params) && params[idx] > 0 {
+			return params[idx]
+		}
+		return def
+	}
+
+	switch final {
+	case 'A':
+		s.moveTo(s.row-param(0, 1), s.col)
+	case 'B', 'e':
+		s.moveTo(s.row+param(0, 1), s.col)
+	case 'C', 'a':
+		s.moveTo(s.row, s.col+param(0, 1))
+	case 'D':
+		s.moveTo(s.row, s.col-param(0, 1))
+	case 'E':
+		s.moveTo(s.row+param(0, 1), 0)
+	case 'F':
+		s.moveTo(s.row-param(0, 1), 0)
+	case 'G', '`':
+		s.moveTo(s.row, param(0, 1)-1)
+	case 'd':
+		s.moveTo(param(0, 1)-1, s.col)
+	case 'H', 'f':
+		s.moveTo(param(0, 1)-1, param(1, 1)-1)
+	case 'J':
+		switch param(0, 0) {
+		case 0:
+			s.erase(s.row, s.col, s.width)
+			for row := s.row + 1; row < s.height; row++ {
+				s.erase(row, 0, s.width)
+			}
+		case 1:
+			for row := 0; row < s.row; row++ {
+				s.erase(row, 0, s.width)
+			}
+			s.erase(s.row, 0, s.col+1)
+		case 2, 3:
+			for row := 0; row < s.height; row++ {
+				s.erase(row, 0, s.width)
+			}
+		}
+	case 'K':
+		switch param(0, 0) {
+		case 0:
+			s.erase(s.row, s.col, s.width)
+		case 1:
+			s.erase(s.row, 0, s.col+1)
+		case 2:
+			s.erase(s.row, 0, s.width)
+		}
+	case 'X':
+		s.erase(s.row, s.col, s.col+param(0, 1))
+	case 'P':
+		line := s.cells[s.row]
+		n := min(param(0, 1), s.width-s.col)
+		copy(line[s.col:], line[s.col+n:])
+		s.erase(s.row, s.width-n, s.width)
+	case '@':
+		line := s.cells[s.row]
+		n := min(param(0, 1), s.width-s.col)
+		copy(line[s.col+n:], line[s.col:])
+		s.erase(s.row, s.col, s.col+n)
+	case 'L':
+		if s.row >= s.top && s.row <= s.bottom {
+			s.insertLines(s.row, param(0, 1))
+			s.col = 0
+		}
+	case 'M':
+		if s.row >= s.top && s.row <= s.bottom {
+			s.deleteLines(s.row, param(0, 1))
+			s.col = 0
+		}
+	case 'S':
+		s.scrollUp(param(0, 1))
+	case 'T':
+		s.scrollDown(param(0, 1))
+	case 'r':
+		top, bottom := param(0, 1)-1, param(1, s.height)-1
+		if top < bottom && bottom < s.height {
+			s.top, s.bottom = top, bottom
+			s.moveTo(0, 0)
+		}
+	case 's':
+		s.saveCursor()
+	case 'u':
+		s.restoreCursor()
+	case 'm':
+		s.sgr(params)
+	}
+}
+
+// alternate switches to (or back from) the alternate screen
+func (s *Screen) alternate(enable bool) {
+	if enable && s.main == nil {
+		s.main, s.mainSaved = s.cells, [2]int{s.row, s.col}
+		s.cells = make([][]Cell, s.height)
+		for idx := range s.cells {
+			s.cells[idx] = s.blankRow()
+		}
+	} else if !enable && s.main != nil {
+		s.cells, s.main = s.main, nil
+		s.moveTo(s.mainSaved[0], s.mainSaved[1])
+	}
+}
+
+func (s *Screen) sgr(params []int) {
+	if len(params) == 0 {
+		params = []int{0}
+	}
+	for idx := 0; idx < len(params); idx++ {
+		switch p := params[idx]; {
+		case p == 0:
+			s.attr = Attr{}
+		case sgrFlags[p] != 0:
+			s.attr.Flags |= sgrFlags[p]
+		case p == 22:
+			s.attr.Flags &^= AttrBold | AttrDim
+		case p == 23:
+			s.attr.Flags &^= AttrItalic
+		case p == 24:
+			s.attr.Flags &^= AttrUnderline
+		case p == 25:
+			s.attr.Flags &^= AttrBlink
+		case p == 27:
+			s.attr.Flags &^= AttrReverse
+		case p == 28:
+			s.attr.Flags &^= AttrHidden
+		case p == 29:
+			s.attr.Flags &^= AttrStrike
+		case p >= 30 && p <= 37:
+			s.attr.FG = IndexedColor(uint8(p - 30))
+		case p == 39:
+			s.attr.FG = 0
+		case p >= 40 && p <= 47:
+			s.attr.BG = IndexedColor(uint8(p - 40))
+		case p == 49:
+			s.attr.BG = 0
+		case p >= 90 && p <= 97:
+			s.attr.FG = IndexedColor(uint8(p - 90 + 8))
+		case p >= 100 && p <= 107:
+			s.attr.BG = IndexedColor(uint8(p - 100 + 8))
+		case p == 38 || p == 48:
+			var color Color
+			switch {
+			case idx+2 < len(params) && params[idx+1] == 5:
+				color = IndexedColor(uint8(params[idx+2]))
+				idx += 2
+			case idx+4 < len(params) && params[idx+1] == 2:
+				color = RGBColor(uint8(params[idx+2]), uint8(params[idx+3]), uint8(params[idx+4]))
+				idx += 4
+			default:
+				// malformed extended color, ignore the rest of the sequence
+				return
+			}
+			if p == 38 {
+				s.attr.FG = color
+			} else {
+				s.attr.BG = color
+			}
+		}
+	}
+}
+
+// parseScreenParams parses the semicolon (or colon) separated numeric
+// parameters of a control sequence, with empty parameters as zero
+func parseScreenParams(text string) (params []int) {
+	if text == "" {
+		return
+	}
+	for _, part := range strings.Split(strings.ReplaceAll(text, ":", ";"), ";") {
+		value, _ := strconv.Atoi(part)
+		params = append(params, value)
+	}
+	return
+}
+
+// Text returns the characters displayed on the screen, one line per row with
+// trailing whitespace removed
+func (s *Screen) Text() (text string) {
+	var buf strings.Builder
+	for _, row := range s.cells {
+		var line strings.Builder
+		for _, cell := range row {
+			if cell.Rune == 0 {
+				line.WriteRune(' ')
+			} else {
+				line.WriteRune(cell.Rune)
+			}
+		}
+		buf.WriteString(strings.TrimRight(line.String(), " "))
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// Attributes returns the attribute layer of the screen: one line per row with
+// a key character for each cell (trailing default cells removed), followed
+// by a legend describing the Attr of each key. Cells with the default Attr
+// are shown as '.'
+//
+// Example:
+//
+//	aaaaa.bbbbb
+//	..cc
+//	a: bold fg=1
+//	b: underline
+//	c: reverse
+func (s *Screen) Attributes() (layer string) {
+	var buf strings.Builder
+	var legend []Attr
+	keys := make(map[Attr]byte)
+	for _, row := range s.cells {
+		var line strings.Builder
+		for _, cell := range row {
+			if cell.Attr == (Attr{}) {
+				line.WriteByte('.')
+				continue
+			}
+			key, ok := keys[cell.Attr]
+			if !ok {
+				key = '?'
+				if len(legend) < len(screenAttrKeys) {
+					key = screenAttrKeys[len(legend)]
+				}
+				keys[cell.Attr] = key
+				legend = append(legend, cell.Attr)
+			}
+			line.WriteByte(key)
+		}
+		buf.WriteString(strings.TrimRight(line.String(), "."))
+		buf.WriteString("\n")
+	}
+	for _, attr := range legend {
+		buf.WriteString(fmt.Sprintf("%c: %s\n", keys[attr], attr.String()))
+	}
+	return buf.String()
+}
+
+// Render returns the Text of the screen, followed by the
+// ScreenAttributesHeader and the Attributes layer when attributes is true
+func (s *Screen) Render(attributes bool) (rendered string) {
+	rendered = s.Text()
+	if attributes {
+		rendered += ScreenAttributesHeader + "\n" + s.Attributes()
+	}
+	return
+}
+
+// GoldenScreen compares the rendered screen with the contents of the named
+// golden file within td, including the attribute layer when attributes is
+// true, failing the test with a diff of the rows which differ. When
+// Updating, the golden file is written with the rendered screen instead
+func GoldenScreen(t testing.TB, td TData, name string, screen *Screen, attributes bool, options ...GoldenOption) {
+	t.Helper()
+	Golden(t, td, name, screen.Render(attributes), options...)
+}
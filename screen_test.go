@@ -0,0 +1,157 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestScreen(t *testing.T) {
+
+	Convey("Text", t, func() {
+		s := NewScreen(10, 3)
+		_, _ = s.WriteString("hello\r\nworld")
+		So(s.Text(), ShouldEqual, "hello\nworld\n\n")
+		row, col := s.Cursor()
+		So(row, ShouldEqual, 1)
+		So(col, ShouldEqual, 5)
+
+		// line feeds do not return the cursor unless AutoCR
+		s = NewScreen(10, 3)
+		_, _ = s.WriteString("ab\ncd")
+		So(s.Text(), ShouldEqual, "ab\n  cd\n\n")
+		s = NewScreen(10, 3)
+		s.AutoCR = true
+		_, _ = s.WriteString("ab\ncd")
+		So(s.Text(), ShouldEqual, "ab\ncd\n\n")
+
+		// wrapping and scrolling
+		s = NewScreen(4, 2)
+		s.AutoCR = true
+		_, _ = s.WriteString("abcdefgh\nij")
+		So(s.Text(), ShouldEqual, "efgh\nij\n")
+
+		// tabs, backspace and UTF-8 split across writes
+		s = NewScreen(12, 1)
+		_, _ = s.WriteString("a\tb\bc")
+		_, _ = s.Write([]byte("\xe2\x94"))
+		_, _ = s.Write([]byte("\x80\xff!"))
+		So(s.Text(), ShouldEqual, "a       c─�!\n")
+	})
+
+	Convey("Cursor and Erase", t, func() {
+		s := NewScreen(6, 3)
+		_, _ = s.WriteString("aaaaaa\r\nbbbbbb\r\ncccccc")
+		_, _ = s.WriteString("\x1b[2;3H\x1b[K")
+		So(s.Text(), ShouldEqual, "aaaaaa\nbb\ncccccc\n")
+		_, _ = s.WriteString("\x1b[1;2H\x1b[1K\x1b[3;5H\x1b[0J")
+		So(s.Text(), ShouldEqual, "  aaaa\nbb\ncccc\n")
+		_, _ = s.WriteString("\x1b[A\x1b[2DX\x1b[BY\x1b[1GZ")
+		So(s.Text(), ShouldEqual, "  aaaa\nbbX\nZccY\n")
+		_, _ = s.WriteString("\x1b[2J\x1b[Hok")
+		So(s.Text(), ShouldEqual, "ok\n\n\n")
+
+		s = NewScreen(6, 1)
+		_, _ = s.WriteString("abcdef\x1b[3G\x1b[2P")
+		So(s.Text(), ShouldEqual, "abef\n")
+		_, _ = s.WriteString("\x1b[1@")
+		So(s.Text(), ShouldEqual, "ab ef\n")
+		_, _ = s.WriteString("\x1b[1;2H\x1b[2X")
+		So(s.Text(), ShouldEqual, "a  ef\n")
+
+		// save and restore
+		s = NewScreen(6, 2)
+		_, _ = s.WriteString("ab\x1b7\x1b[2;5Hz\x1b8c")
+		So(s.Text(), ShouldEqual, "abc\n    z\n")
+	})
+
+	Convey("Lines and Scrolling", t, func() {
+		s := NewScreen(3, 4)
+		_, _ = s.WriteString("1\r\n2\r\n3\r\n4")
+		_, _ = s.WriteString("\x1b[2H\x1b[L")
+		So(s.Text(), ShouldEqual, "1\n\n2\n3\n")
+		_, _ = s.WriteString("\x1b[2M")
+		So(s.Text(), ShouldEqual, "1\n3\n\n\n")
+
+		// scrolling region
+		s = NewScreen(3, 4)
+		_, _ = s.WriteString("h\x1b[4;1Hf\x1b[2;3r\x1b[2Ha\r\nb\r\nc")
+		So(s.Text(), ShouldEqual, "h\nb\nc\nf\n")
+		_, _ = s.WriteString("\x1b[2H\x1bM")
+		So(s.Text(), ShouldEqual, "h\n\nb\nf\n")
+
+		// alternate screen
+		s = NewScreen(4, 2)
+		_, _ = s.WriteString("main\x1b[?1049hx\x1b[?25l")
+		So(s.Text(), ShouldEqual, "\nx\n")
+		_, _ = s.WriteString("\x1b[?1049l")
+		So(s.Text(), ShouldEqual, "main\n\n")
+	})
+
+	Convey("Attributes", t, func() {
+		s := NewScreen(8, 2)
+		_, _ = s.WriteString("\x1b]0;title\x07\x1b(B")
+		_, _ = s.WriteString("\x1b[1;31mab\x1b[22mc\x1b[0m d\r\n")
+		_, _ = s.WriteString("\x1b[38;5;200;48;2;1;2;3mx\x1b[39;49;4;7my\x1b[m")
+		So(s.Text(), ShouldEqual, "abc d\nxy\n")
+		So(s.Cell(0, 0).Attr, ShouldResemble, Attr{Flags: AttrBold, FG: IndexedColor(1)})
+		So(s.Cell(0, 2).Attr, ShouldResemble, Attr{FG: IndexedColor(1)})
+		So(s.Cell(9, 9), ShouldResemble, Cell{})
+		So(s.Attributes(), ShouldEqual, ""+
+			"aab\n"+
+			"cd\n"+
+			"a: bold fg=1\n"+
+			"b: fg=1\n"+
+			"c: fg=200 bg=#010203\n"+
+			"d: underline reverse\n",
+		)
+		So(s.Render(true), ShouldEqual, "abc d\nxy\n"+ScreenAttributesHeader+"\n"+s.Attributes())
+		So(s.Render(false), ShouldEqual, s.Text())
+
+		// erasing uses the current background color
+		s = NewScreen(3, 1)
+		_, _ = s.WriteString("\x1b[44m\x1b[2K")
+		So(s.Attributes(), ShouldEqual, "aaa\na: bg=4\n")
+	})
+
+	Convey("GoldenScreen", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		s := NewScreen(10, 2)
+		_, _ = s.WriteString("\x1b[7m menu \x1b[m\r\nready")
+		mt := newMockT("TestScreen")
+		withUpdating(func() {
+			GoldenScreen(mt, tmpd, "screen.txt", s, true)
+		})
+		So(mt.failed(), ShouldEqual, "")
+		So(tmpd.F("screen.txt"), ShouldEqual, " menu\nready\n"+ScreenAttributesHeader+"\naaaaaa\n\na: reverse\n")
+
+		mt = newMockT("TestScreen")
+		GoldenScreen(mt, tmpd, "screen.txt", s, true)
+		So(mt.failed(), ShouldEqual, "")
+
+		_, _ = s.WriteString("\x1b[1;2H\x1b[4mMENU")
+		mt = newMockT("TestScreen")
+		GoldenScreen(mt, tmpd, "screen.txt", s, true)
+		So(mt.failed(), ShouldStartWith, "golden file \"screen.txt\" mismatch:\n")
+		So(mt.failed(), ShouldContainSubstring, "+   1 |  MENU")
+		So(mt.failed(), ShouldContainSubstring, "+   4 | abbbba")
+	})
+
+}
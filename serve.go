@@ -0,0 +1,185 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ServeOption configures the behaviour of the server started by Serve. Each
+// option applies to the request paths matching its pattern, which is either
+// empty (matching all paths) or a path.Match pattern such as "/api/*.json"
+type ServeOption func(cfg *serveConfig)
+
+// WithLatency delays the responses to requests matching the pattern
+func WithLatency(pattern string, latency time.Duration) ServeOption {
+	return func(cfg *serveConfig) {
+		cfg.rules = append(cfg.rules, &serveRule{pattern: pattern, latency: latency})
+	}
+}
+
+// WithBandwidth throttles the response bodies of requests matching the
+// pattern to the given number of bytes per second
+func WithBandwidth(pattern string, bytesPerSecond int) ServeOption {
+	return func(cfg *serveConfig) {
+		cfg.rules = append(cfg.rules, &serveRule{pattern: pattern, bandwidth: bytesPerSecond})
+	}
+}
+
+// WithStatus responds to the first count requests matching the pattern with
+// the given error status instead of the fixture content, or to all matching
+// requests when count is zero or less
+func WithStatus(pattern string, status, count int) ServeOption {
+	if count <= 0 {
+		count = -1
+	}
+	return func(cfg *serveConfig) {
+		cfg.rules = append(cfg.rules, &serveRule{pattern: pattern, status: status, remaining: count})
+	}
+}
+
+type serveRule struct {
+	pattern   string
+	latency   time.Duration
+	bandwidth int
+	status    int
+	remaining int
+}
+
+func (r *serveRule) match(urlPath string) (matched bool) {
+	if r.pattern == "" {
+		return true
+	}
+	matched, _ = path.Match(r.pattern, urlPath)
+	return
+}
+
+type serveConfig struct {
+	sync.Mutex
+	rules []*serveRule
+}
+
+// apply returns the latency, bandwidth and injected status for the request
+// path, where the last matching latency and bandwidth rules win and the
+// first matching status rule with requests remaining is used (and counted)
+func (cfg *serveConfig) apply(urlPath string) (latency time.Duration, bandwidth, status int) {
+	cfg.Lock()
+	defer cfg.Unlock()
+	for _, rule := range cfg.rules {
+		if !rule.match(urlPath) {
+			continue
+		}
+		if rule.latency > 0 {
+			latency = rule.latency
+		}
+		if rule.bandwidth > 0 {
+			bandwidth = rule.bandwidth
+		}
+		if rule.status > 0 && status == 0 && rule.remaining != 0 {
+			status = rule.status
+			if rule.remaining > 0 {
+				rule.remaining--
+			}
+		}
+	}
+	return
+}
+
+// Serve starts an httptest.Server serving the files of td, which is closed
+// when the test completes. The server provides content types, Range
+// requests, conditional requests with ETags (the sha256 of the file) and
+// directory listings, with optional latency, throttled bandwidth and
+// injected error statuses for exercising client retry logic
+func Serve(t testing.TB, td TData, options ...ServeOption) (server *httptest.Server) {
+	t.Helper()
+	cfg := &serveConfig{}
+	for _, option := range options {
+		option(cfg)
+	}
+
+	files := http.FileServer(http.Dir(td.Path()))
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		latency, bandwidth, status := cfg.apply(r.URL.Path)
+
+		if latency > 0 {
+			select {
+			case <-time.After(latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if status > 0 {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		if bandwidth > 0 {
+			w = &throttledWriter{ResponseWriter: w, ctx: r.Context(), rate: bandwidth}
+		}
+
+		// http.FileServer honours an ETag set before it is called
+		filename := filepath.Join(td.Path(), filepath.FromSlash(path.Clean("/"+r.URL.Path)))
+		if data, err := os.ReadFile(filename); err == nil {
+			w.Header().Set("ETag", `"`+sha256Hex(data)+`"`)
+			trackUsage(filename)
+		}
+		files.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return
+}
+
+// throttledWriter limits the rate at which a response body is written,
+// stopping early with the error of the request context once it is done
+type throttledWriter struct {
+	http.ResponseWriter
+	ctx  context.Context
+	rate int
+}
+
+func (w *throttledWriter) Write(p []byte) (n int, err error) {
+	// write in chunks of a tenth of a second
+	chunk := max(w.rate/10, 1)
+	for len(p) > 0 {
+		size := min(chunk, len(p))
+		timer := time.NewTimer(time.Duration(size) * time.Second / time.Duration(w.rate))
+		select {
+		case <-timer.C:
+		case <-w.ctx.Done():
+			timer.Stop()
+			err = w.ctx.Err()
+			return
+		}
+		var written int
+		written, err = w.ResponseWriter.Write(p[:size])
+		if n += written; err != nil {
+			return
+		}
+		if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		p = p[size:]
+	}
+	return
+}
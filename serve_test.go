@@ -0,0 +1,154 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestServe(t *testing.T) {
+
+	get := func(url string, header ...string) (response *http.Response, body string) {
+		request, err := http.NewRequest(http.MethodGet, url, nil)
+		So(err, ShouldBeNil)
+		for idx := 0; idx+1 < len(header); idx += 2 {
+			request.Header.Set(header[idx], header[idx+1])
+		}
+		response, err = http.DefaultClient.Do(request)
+		So(err, ShouldBeNil)
+		defer response.Body.Close()
+		data, err := io.ReadAll(response.Body)
+		So(err, ShouldBeNil)
+		return response, string(data)
+	}
+
+	Convey("Serve", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"data.json":       File(`{"key": "value"}`),
+			"docs/readme.txt": File("0123456789"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		server := Serve(t, tmpd)
+
+		response, body := get(server.URL + "/data.json")
+		So(response.StatusCode, ShouldEqual, http.StatusOK)
+		So(response.Header.Get("Content-Type"), ShouldEqual, "application/json")
+		So(body, ShouldEqual, `{"key": "value"}`)
+		etag := response.Header.Get("ETag")
+		So(etag, ShouldEqual, `"`+sha256Hex([]byte(`{"key": "value"}`))+`"`)
+
+		response, _ = get(server.URL+"/data.json", "If-None-Match", etag)
+		So(response.StatusCode, ShouldEqual, http.StatusNotModified)
+
+		response, body = get(server.URL+"/docs/readme.txt", "Range", "bytes=2-4")
+		So(response.StatusCode, ShouldEqual, http.StatusPartialContent)
+		So(response.Header.Get("Content-Range"), ShouldEqual, "bytes 2-4/10")
+		So(body, ShouldEqual, "234")
+
+		response, body = get(server.URL + "/docs/")
+		So(response.StatusCode, ShouldEqual, http.StatusOK)
+		So(body, ShouldContainSubstring, `<a href="readme.txt">readme.txt</a>`)
+
+		response, _ = get(server.URL + "/missing.txt")
+		So(response.StatusCode, ShouldEqual, http.StatusNotFound)
+	})
+
+	Convey("Options", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"fast.txt":     File("fast"),
+			"slow.txt":     File("slow"),
+			"flaky.txt":    File("flaky"),
+			"large.bin":    File(strings.Repeat("x", 200)),
+			"down/any.txt": File("down"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		server := Serve(t, tmpd,
+			WithLatency("/slow.txt", 100*time.Millisecond),
+			WithBandwidth("/*.bin", 1000),
+			WithStatus("/flaky.txt", http.StatusServiceUnavailable, 2),
+			WithStatus("/down/*", http.StatusInternalServerError, 0),
+		)
+
+		start := time.Now()
+		_, body := get(server.URL + "/fast.txt")
+		So(body, ShouldEqual, "fast")
+		So(time.Since(start), ShouldBeLessThan, 100*time.Millisecond)
+
+		start = time.Now()
+		_, body = get(server.URL + "/slow.txt")
+		So(body, ShouldEqual, "slow")
+		So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 100*time.Millisecond)
+
+		// 200 bytes at 1000 bytes per second
+		start = time.Now()
+		_, body = get(server.URL + "/large.bin")
+		So(body, ShouldHaveLength, 200)
+		So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 200*time.Millisecond)
+
+		var statuses []int
+		for idx := 0; idx < 3; idx++ {
+			response, _ := get(server.URL + "/flaky.txt")
+			statuses = append(statuses, response.StatusCode)
+		}
+		So(statuses, ShouldEqual, []int{503, 503, 200})
+
+		for idx := 0; idx < 3; idx++ {
+			response, _ := get(server.URL + "/down/any.txt")
+			So(response.StatusCode, ShouldEqual, http.StatusInternalServerError)
+		}
+	})
+
+	Convey("Throttled Cancel", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		recorder := httptest.NewRecorder()
+		w := &throttledWriter{ResponseWriter: &cancellingWriter{ResponseWriter: recorder, cancel: cancel}, ctx: ctx, rate: 10}
+
+		// 100 bytes at 10 bytes per second, cancelled by the first
+		start := time.Now()
+		n, err := w.Write([]byte(strings.Repeat("x", 100)))
+		So(errors.Is(err, context.Canceled), ShouldBeTrue)
+		So(n, ShouldEqual, 1)
+		So(recorder.Body.String(), ShouldEqual, "x")
+		So(time.Since(start), ShouldBeLessThan, time.Second)
+	})
+
+}
+
+// cancellingWriter cancels its context once the first write is made
+type cancellingWriter struct {
+	http.ResponseWriter
+	cancel context.CancelFunc
+}
+
+func (w *cancellingWriter) Write(p []byte) (n int, err error) {
+	n, err = w.ResponseWriter.Write(p)
+	w.cancel()
+	return
+}
@@ -0,0 +1,291 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	clPath "github.com/go-corelibs/path"
+)
+
+var (
+	// SnapshotsDir is the name of the directory, within the TestData, where
+	// Snapshot files are stored
+	SnapshotsDir = "__snapshots__"
+	// SnapshotExtension is the file extension of Snapshot files
+	SnapshotExtension = ".snap"
+)
+
+var rxSnapshotHeader = regexp.MustCompile(`^--- snapshot (\d+) ---$`)
+
+var snapshots = struct {
+	states map[string]*snapshotState
+	sync.Mutex
+}{
+	states: make(map[string]*snapshotState),
+}
+
+type snapshotState struct {
+	// given is the snapshot file name of the test and name is the most
+	// specific variant of it which exists, see ResolveGolden
+	given   string
+	name    string
+	td      TData
+	want    []string
+	got     []string
+	missing bool
+	// mismatched is true when any snapshot taken was missing or different
+	mismatched bool
+	sync.Mutex
+}
+
+// Snapshot compares a Pretty representation of the value with the next
+// snapshot stored for the current test, failing the test if they differ. The
+// snapshots are stored in the top-level testdata directory of the calling
+// package, in a `__snapshots__/<TestName>.snap` file and are numbered in the
+// order that Snapshot is called. When Updating, the snapshot file is
+// rewritten with all the snapshots taken once the test completes and when
+// Reviewing, a mismatched snapshot file has its pending replacement written
+// once the test completes. As with golden files, the most specific variant of
+// the snapshot file which exists for the CurrentPlatform is used, such as
+// `__snapshots__/<TestName>.linux.snap`, see Platform.Variants
+func Snapshot(t testing.TB, value any) {
+	t.Helper()
+	SnapshotIn(t, newTestData(1, DefaultTestData), value)
+}
+
+// SnapshotIn is the same as Snapshot except storing the snapshot files within
+// the given TData instead of the calling package's testdata directory
+func SnapshotIn(t testing.TB, td TData, value any) {
+	t.Helper()
+	noteGuardTest(t)
+	checkChecksums(t, td)
+
+	state, err := getSnapshotState(t, td)
+	if err != nil {
+		t.Fatalf("error reading snapshots %q: %v", snapshotFile(t.Name()), err)
+		return
+	}
+
+	got := Pretty(value) + "\n"
+	state.Lock()
+	state.got = append(state.got, got)
+	number := len(state.got)
+	state.Unlock()
+
+	if updatingGolden(t, state.given, state.name) {
+		return
+	} else if state.missing || number > len(state.want) {
+		t.Errorf("snapshot %q #%d not found, run with -%s to create it", state.name, number, UpdateFlag)
+		state.mismatch()
+	} else if want := state.want[number-1]; want != got {
+		t.Errorf("snapshot %q #%d mismatch:\n%s", state.name, number, textDiff(want, got))
+		state.mismatch()
+	}
+}
+
+func snapshotFile(testName string) (name string) {
+	return filepath.Join(SnapshotsDir, filepath.FromSlash(testName)+SnapshotExtension)
+}
+
+func getSnapshotState(t testing.TB, td TData) (state *snapshotState, err error) {
+	snapshots.Lock()
+	defer snapshots.Unlock()
+
+	key := td.Path() + "\x00" + t.Name()
+	if state = snapshots.states[key]; state != nil {
+		return
+	}
+
+	given := snapshotFile(t.Name())
+	state = &snapshotState{given: given, name: ResolveGolden(td, given, goldenPlatform), td: td}
+	var data []byte
+	if data, err = readGolden(td, state.name); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return
+		}
+		err = nil
+		state.missing = true
+	} else if state.want, err = parseSnapshots(string(data)); err != nil {
+		return
+	}
+
+	snapshots.states[key] = state
+	t.Cleanup(func() {
+		snapshots.Lock()
+		delete(snapshots.states, key)
+		snapshots.Unlock()
+		if updatingGolden(t, state.given, state.name) {
+			state.update(t)
+		} else if Reviewing() {
+			state.review(t)
+		}
+	})
+	return
+}
+
+func (s *snapshotState) mismatch() {
+	s.Lock()
+	s.mismatched = true
+	s.Unlock()
+}
+
+// contents returns the snapshot file contents of the snapshots taken
+func (s *snapshotState) contents() (data []byte) {
+	var buf strings.Builder
+	for idx, text := range s.got {
+		buf.WriteString(fmt.Sprintf("--- snapshot %d ---\n", idx+1))
+		buf.WriteString(text)
+	}
+	return []byte(buf.String())
+}
+
+// review writes the pending replacement of a mismatched snapshot file, or
+// removes any stale replacement when all the snapshots matched
+func (s *snapshotState) review(t testing.TB) {
+	s.Lock()
+	defer s.Unlock()
+	if s.mismatched {
+		reviewPending(t, s.td, s.name, s.contents())
+	} else {
+		discardPending(s.td, s.name)
+	}
+}
+
+func (s *snapshotState) update(t testing.TB) {
+	s.Lock()
+	defer s.Unlock()
+	if !s.missing && strings.Join(s.want, "\x00") == strings.Join(s.got, "\x00") {
+		return
+	}
+	if written, err := updateGolden(t, s.td, s.name, s.contents()); err != nil {
+		t.Errorf("error updating snapshot %q: %v", s.name, err)
+	} else if written {
+		t.Logf("updated snapshot: %s", s.name)
+	}
+}
+
+func parseSnapshots(data string) (sections []string, err error) {
+	var current *strings.Builder
+	for idx, line := range splitLines(data) {
+		if m := rxSnapshotHeader.FindStringSubmatch(strings.TrimSuffix(line, "\n")); m != nil {
+			if number, _ := strconv.Atoi(m[1]); number != len(sections)+1 {
+				err = fmt.Errorf("%w: line %d: expected snapshot %d, found %d", ErrSnapshotSyntax, idx+1, len(sections)+1, number)
+				return
+			}
+			if current != nil {
+				sections[len(sections)-1] = current.String()
+			}
+			sections = append(sections, "")
+			current = &strings.Builder{}
+			continue
+		} else if current == nil {
+			err = fmt.Errorf("%w: line %d: expected a snapshot header", ErrSnapshotSyntax, idx+1)
+			return
+		}
+		current.WriteString(line)
+	}
+	if current != nil {
+		sections[len(sections)-1] = current.String()
+	}
+	return
+}
+
+// OrphanedSnapshots returns the list of snapshot files within td which are
+// not associated with any of the top-level tests found in the Go module
+// containing the td directory (an ErrNotFound error is returned if td is not
+// within a Go module). All `*_test.go` files of the module are parsed,
+// skipping testdata directories, hidden directories and any nested modules.
+//
+// Subtest names are only known when the tests run, so the snapshot files of
+// subtests, such as `__snapshots__/TestName/subtest.snap`, are only checked
+// by the top-level test name: the file of a subtest which was removed or
+// renamed is not reported while the top-level test still exists
+func OrphanedSnapshots(td TData) (orphans []string, err error) {
+	var moduleDir string
+	var tests map[string]struct{}
+	if moduleDir, err = findModuleRoot(td.Path()); err != nil {
+		return
+	} else if tests, err = findModuleTests(moduleDir); err != nil {
+		return
+	}
+
+	root := td.Join(SnapshotsDir)
+	if !clPath.IsDir(root) {
+		return
+	}
+	for _, file := range td.LAFH(SnapshotsDir) {
+		if !strings.HasSuffix(file, SnapshotExtension) {
+			continue
+		}
+		rel, _ := filepath.Rel(root, file)
+		top := strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]
+		// test function names have no dots, which leaves out the extension
+		// and any variant suffix
+		top, _, _ = strings.Cut(top, ".")
+		if _, present := tests[top]; !present {
+			orphans = append(orphans, filepath.Join(SnapshotsDir, rel))
+		}
+	}
+	sort.Strings(orphans)
+	return
+}
+
+func findModuleTests(moduleDir string) (tests map[string]struct{}, err error) {
+	tests = make(map[string]struct{})
+	fset := token.NewFileSet()
+	err = filepath.WalkDir(moduleDir, func(path string, d fs.DirEntry, ee error) error {
+		if ee != nil {
+			return ee
+		}
+		if d.IsDir() {
+			if path == moduleDir {
+				return nil
+			}
+			name := d.Name()
+			if clPath.IsHidden(name) || name == "testdata" || name == "vendor" || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			} else if clPath.IsFile(filepath.Join(path, "go.mod")) {
+				return filepath.SkipDir
+			}
+			return nil
+		} else if !strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		file, pe := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if pe != nil {
+			return pe
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && strings.HasPrefix(fn.Name.Name, "Test") {
+				tests[fn.Name.Name] = struct{}{}
+			}
+		}
+		return nil
+	})
+	return
+}
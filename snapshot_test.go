@@ -0,0 +1,249 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type prettyNode struct {
+	Name     string
+	Children []*prettyNode
+	Parent   *prettyNode
+	attrs    map[string]int
+}
+
+func TestPretty(t *testing.T) {
+
+	Convey("Scalars", t, func() {
+		So(Pretty(nil), ShouldEqual, "nil")
+		So(Pretty(true), ShouldEqual, "true")
+		So(Pretty(-10), ShouldEqual, "-10")
+		So(Pretty(uint8(10)), ShouldEqual, "10")
+		So(Pretty(1.5), ShouldEqual, "1.5")
+		So(Pretty("text\n"), ShouldEqual, `"text\n"`)
+		So(Pretty(EntryType(2)), ShouldEqual, "tdata.EntryType(2)")
+		So(Pretty([]byte("bytes")), ShouldEqual, `[]uint8("bytes")`)
+		So(Pretty(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)), ShouldEqual, "time.Time(2024-01-02T03:04:05Z)")
+		So(Pretty(errors.New("boom")), ShouldEqual, `*errors.errorString("boom")`)
+		So(Pretty((*int)(nil)), ShouldEqual, "(*int)(nil)")
+		So(Pretty(func() {}), ShouldEqual, "(func())(<non-nil>)")
+	})
+
+	Convey("Composites", t, func() {
+		root := &prettyNode{Name: "root", attrs: map[string]int{"b": 2, "a": 1}}
+		root.Children = []*prettyNode{{Name: "child", Parent: root}}
+		So(Pretty(root), ShouldEqual, `&tdata.prettyNode{
+  Name: "root",
+  Children: []*tdata.prettyNode{
+    &tdata.prettyNode{
+      Name: "child",
+      Children: []*tdata.prettyNode(nil),
+      Parent: &<cycle>,
+      attrs: map[string]int(nil),
+    },
+  },
+  Parent: (*tdata.prettyNode)(nil),
+  attrs: map[string]int{
+    "a": 1,
+    "b": 2,
+  },
+}`)
+		So(Pretty(map[int]any{}), ShouldEqual, "map[int]interface {}{}")
+		So(Pretty([2]string{"x", "y"}), ShouldEqual, "[2]string{\n  \"x\",\n  \"y\",\n}")
+		So(Pretty(struct{}{}), ShouldEqual, "struct {}{}")
+
+		// unexported time.Time and error values
+		stamped := prettyStamped{
+			at:      time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+			fixed:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("X", 3600)),
+			current: time.Now(),
+			err:     errors.New("boom"),
+		}
+		text := Pretty(stamped)
+		So(text, ShouldStartWith, `tdata.prettyStamped{
+  at: time.Time(2024-01-02T03:04:05.000000006Z),
+  fixed: time.Time(2024-01-02T03:04:05+01:00),
+  current: time.Time(`+stamped.current.Format(time.RFC3339Nano)+`),
+  err: &errors.errorString{
+    s: "boom",
+  },
+  zero: time.Time(0001-01-01T00:00:00Z),
+}`)
+		So(text, ShouldNotContainSubstring, "Location")
+
+		// shared, non-recursive pointers are printed each time
+		shared := &prettyNode{Name: "shared"}
+		So(Pretty([]*prettyNode{shared, shared}), ShouldNotContainSubstring, "<cycle>")
+	})
+
+}
+
+type prettyStamped struct {
+	at      time.Time
+	fixed   time.Time
+	current time.Time
+	err     error
+	zero    time.Time
+}
+
+func TestSnapshot(t *testing.T) {
+
+	Convey("SnapshotIn", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		mt := newMockT("TestThing/sub_case")
+		SnapshotIn(mt, tmpd, map[string]int{"one": 1})
+		So(mt.failed(), ShouldContainSubstring, `snapshot "__snapshots__/TestThing/sub_case.snap" #1 not found`)
+		mt.runCleanup()
+
+		mt = newMockT("TestThing/sub_case")
+		withUpdating(func() {
+			SnapshotIn(mt, tmpd, map[string]int{"one": 1})
+			SnapshotIn(mt, tmpd, "second")
+			mt.runCleanup()
+		})
+		So(mt.failed(), ShouldEqual, "")
+		So(mt.logs, ShouldEqual, []string{"updated snapshot: __snapshots__/TestThing/sub_case.snap"})
+		So(tmpd.F("__snapshots__/TestThing/sub_case.snap"), ShouldEqual, ""+
+			"--- snapshot 1 ---\n"+
+			"map[string]int{\n"+
+			"  \"one\": 1,\n"+
+			"}\n"+
+			"--- snapshot 2 ---\n"+
+			"\"second\"\n",
+		)
+
+		mt = newMockT("TestThing/sub_case")
+		SnapshotIn(mt, tmpd, map[string]int{"one": 1})
+		SnapshotIn(mt, tmpd, "second")
+		mt.runCleanup()
+		So(mt.failed(), ShouldEqual, "")
+
+		mt = newMockT("TestThing/sub_case")
+		SnapshotIn(mt, tmpd, map[string]int{"one": 2})
+		SnapshotIn(mt, tmpd, "second")
+		SnapshotIn(mt, tmpd, "third")
+		mt.runCleanup()
+		So(mt.failed(), ShouldContainSubstring, `snapshot "__snapshots__/TestThing/sub_case.snap" #1 mismatch:`)
+		So(mt.failed(), ShouldContainSubstring, `+   2 |   "one": 2,`)
+		So(mt.failed(), ShouldContainSubstring, `snapshot "__snapshots__/TestThing/sub_case.snap" #3 not found`)
+
+		// unexported time.Time fields are stable
+		stamped := prettyStamped{at: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+		mt = newMockT("TestStamped")
+		withUpdating(func() {
+			SnapshotIn(mt, tmpd, stamped)
+			mt.runCleanup()
+		})
+		So(mt.failed(), ShouldEqual, "")
+		So(tmpd.F("__snapshots__/TestStamped.snap"), ShouldContainSubstring, "  at: time.Time(2024-01-02T03:04:05Z),\n")
+		mt = newMockT("TestStamped")
+		SnapshotIn(mt, tmpd, stamped)
+		mt.runCleanup()
+		So(mt.failed(), ShouldEqual, "")
+	})
+
+	Convey("Snapshot Variants", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"__snapshots__/TestThing.snap":       File("--- snapshot 1 ---\n\"generic\"\n"),
+			"__snapshots__/TestThing.linux.snap": File("--- snapshot 1 ---\n\"linux\"\n"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		withPlatform(Platform{GOOS: "linux", GOARCH: "amd64"}, func() {
+			mt := newMockT("TestThing")
+			SnapshotIn(mt, tmpd, "linux")
+			mt.runCleanup()
+			So(mt.failed(), ShouldEqual, "")
+
+			mt = newMockT("TestThing")
+			SnapshotIn(mt, tmpd, "generic")
+			mt.runCleanup()
+			So(mt.failed(), ShouldContainSubstring, `snapshot "__snapshots__/TestThing.linux.snap" #1 mismatch:`)
+
+			// scoped by the variant name
+			mt = newMockT("TestThing")
+			withUpdateScope("__snapshots__/*.linux.snap", func() {
+				SnapshotIn(mt, tmpd, "updated")
+				mt.runCleanup()
+			})
+			So(mt.failed(), ShouldEqual, "")
+			So(mt.logs, ShouldEqual, []string{"updated snapshot: __snapshots__/TestThing.linux.snap"})
+		})
+		So(tmpd.F("__snapshots__/TestThing.linux.snap"), ShouldEqual, "--- snapshot 1 ---\n\"updated\"\n")
+		So(tmpd.F("__snapshots__/TestThing.snap"), ShouldEqual, "--- snapshot 1 ---\n\"generic\"\n")
+
+		withPlatform(Platform{GOOS: "windows"}, func() {
+			mt := newMockT("TestThing")
+			SnapshotIn(mt, tmpd, "generic")
+			mt.runCleanup()
+			So(mt.failed(), ShouldEqual, "")
+		})
+	})
+
+	Convey("Parse Errors", t, func() {
+		_, err := parseSnapshots("no header\n")
+		So(errors.Is(err, ErrSnapshotSyntax), ShouldBeTrue)
+		_, err = parseSnapshots("--- snapshot 2 ---\n")
+		So(errors.Is(err, ErrSnapshotSyntax), ShouldBeTrue)
+	})
+
+	Convey("OrphanedSnapshots", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		_, err = OrphanedSnapshots(tmpd)
+		So(errors.Is(err, ErrNotFound), ShouldBeTrue)
+
+		So(Tree{
+			"go.mod":                               File("module example\n"),
+			"a_test.go":                            File("package a\nfunc TestKept(t *testing.T) {}\nfunc helper() {}\n"),
+			"sub/b_test.go":                        File("package sub\nfunc TestOther(t *testing.T) {}\n"),
+			"nested/go.mod":                        File("module nested\n"),
+			"nested/c_test.go":                     File("package c\nfunc TestGone(t *testing.T) {}\n"),
+			"testdata/__snapshots__/TestKept.snap": File(""),
+			"testdata/__snapshots__/TestKept/sub.snap":   File(""),
+			"testdata/__snapshots__/TestKept.linux.snap": File(""),
+			"testdata/__snapshots__/TestGone.linux.snap": File(""),
+			"testdata/__snapshots__/TestOther.snap":      File(""),
+			"testdata/__snapshots__/TestGone.snap":       File(""),
+			"testdata/__snapshots__/TestGone/case.snap":  File(""),
+			"testdata/__snapshots__/notes.txt":           File(""),
+		}.Apply(tmpd), ShouldBeNil)
+
+		orphans, err := OrphanedSnapshots(&tdata{path: tmpd.Join("testdata")})
+		So(err, ShouldBeNil)
+		So(orphans, ShouldEqual, []string{
+			"__snapshots__/TestGone.linux.snap",
+			"__snapshots__/TestGone.snap",
+			"__snapshots__/TestGone/case.snap",
+		})
+
+		orphans, err = OrphanedSnapshots(New())
+		So(err, ShouldBeNil)
+		So(orphans, ShouldBeEmpty)
+	})
+
+}
@@ -0,0 +1,288 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// CaseValidator is implemented by table case types which check their own
+// fields, Cases calls Validate on each decoded record
+type CaseValidator interface {
+	Validate() (err error)
+}
+
+// Cases reads the named JSON or CSV table file within td and decodes each
+// record into a T, which must be a struct with a name field: either a
+// string field tagged `tdata:"name"` or a string field called Name
+//
+// JSON tables are an array of objects, decoded with encoding/json and
+// rejecting unknown fields. CSV tables have a header row naming the columns,
+// matched (case-insensitively) to the `csv` tag, the `json` tag or the name
+// of each field. CSV values are converted to the field's type: strings,
+// booleans, decimal integers, floats, time.Duration or any
+// encoding.TextUnmarshaler, with empty values leaving the zero value. Fields
+// promoted through nil embedded struct pointers have the pointer allocated
+//
+// Every record must have a unique, non-empty name and must pass Validate if
+// T is a CaseValidator. Errors wrap ErrCaseInvalid and include the file name
+// and zero-based record index
+func Cases[T any](td TData, name string) (cases []T, err error) {
+	var nameField []int
+	if nameField, err = tableNameField(reflect.TypeFor[T]()); err != nil {
+		return
+	}
+
+	var data []byte
+	if data, err = os.ReadFile(td.Join(name)); err != nil {
+		return
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(name)); ext {
+	case ".json":
+		cases, err = decodeJSONTable[T](name, data)
+	case ".csv":
+		cases, err = decodeCSVTable[T](name, data)
+	default:
+		err = fmt.Errorf("%w: %q", ErrCasesFormat, ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]int)
+	for idx := range cases {
+		value := reflect.ValueOf(&cases[idx]).Elem()
+		var caseName string
+		if field, ee := value.FieldByIndexErr(nameField); ee == nil {
+			// not when promoted from a nil embedded struct pointer
+			caseName = field.String()
+		}
+		if caseName == "" {
+			return nil, tableError(name, idx, errors.New("missing name"))
+		} else if first, present := seen[caseName]; present {
+			return nil, tableError(name, idx, fmt.Errorf("duplicate name %q, first used by record %d", caseName, first))
+		}
+		seen[caseName] = idx
+		if validator, ok := value.Addr().Interface().(CaseValidator); ok {
+			if ee := validator.Validate(); ee != nil {
+				return nil, tableError(name, idx, ee)
+			}
+		}
+	}
+	return
+}
+
+// RunCases loads the named table file within td with Cases and runs fn as a
+// subtest, named after the case, for each of the records. RunCases fails the
+// test immediately if the table cannot be loaded
+func RunCases[T any](t *testing.T, td TData, name string, fn func(t *testing.T, c T)) {
+	t.Helper()
+	cases, err := Cases[T](td, name)
+	if err != nil {
+		t.Fatalf("error loading cases: %v", err)
+		return
+	}
+	nameField, _ := tableNameField(reflect.TypeFor[T]())
+	for _, c := range cases {
+		t.Run(reflect.ValueOf(c).FieldByIndex(nameField).String(), func(t *testing.T) {
+			noteGuardTest(t)
+			checkChecksums(t, td)
+			fn(t, c)
+		})
+	}
+}
+
+func tableError(name string, idx int, cause error) (err error) {
+	return fmt.Errorf("%w: %s record %d: %v", ErrCaseInvalid, name, idx, cause)
+}
+
+// tableNameField returns the index of the name field of the struct type
+func tableNameField(rt reflect.Type) (index []int, err error) {
+	if rt.Kind() != reflect.Struct {
+		err = fmt.Errorf("%w: %v is not a struct", ErrCaseInvalid, rt)
+		return
+	}
+	var named []int
+	for _, field := range reflect.VisibleFields(rt) {
+		if !field.IsExported() || field.Type.Kind() != reflect.String {
+			continue
+		}
+		if field.Tag.Get("tdata") == "name" {
+			return field.Index, nil
+		} else if field.Name == "Name" && named == nil {
+			named = field.Index
+		}
+	}
+	if named == nil {
+		err = fmt.Errorf("%w: %v has no name field", ErrCaseInvalid, rt)
+	}
+	return named, err
+}
+
+func decodeJSONTable[T any](name string, data []byte) (cases []T, err error) {
+	var records []json.RawMessage
+	if err = json.Unmarshal(data, &records); err != nil {
+		err = fmt.Errorf("%w: %s: %v", ErrCaseInvalid, name, err)
+		return
+	}
+	cases = make([]T, len(records))
+	for idx, record := range records {
+		decoder := json.NewDecoder(bytes.NewReader(record))
+		decoder.DisallowUnknownFields()
+		if ee := decoder.Decode(&cases[idx]); ee != nil {
+			return nil, tableError(name, idx, ee)
+		}
+	}
+	return
+}
+
+func decodeCSVTable[T any](name string, data []byte) (cases []T, err error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	var header []string
+	if header, err = reader.Read(); err != nil {
+		err = fmt.Errorf("%w: %s: reading header: %v", ErrCaseInvalid, name, err)
+		return
+	}
+
+	rt := reflect.TypeFor[T]()
+	columns := make([][]int, len(header))
+	for col, label := range header {
+		label = strings.TrimSpace(label)
+		for _, field := range reflect.VisibleFields(rt) {
+			if !field.IsExported() || field.Anonymous {
+				continue
+			}
+			candidates := []string{field.Name, tagName(field.Tag.Get("json")), tagName(field.Tag.Get("csv"))}
+			for _, candidate := range candidates {
+				if candidate != "" && strings.EqualFold(candidate, label) {
+					columns[col] = field.Index
+				}
+			}
+		}
+		if columns[col] == nil {
+			err = fmt.Errorf("%w: %s: unknown column %q", ErrCaseInvalid, name, label)
+			return
+		}
+	}
+
+	for idx := 0; ; idx++ {
+		var record []string
+		if record, err = reader.Read(); errors.Is(err, io.EOF) {
+			return cases, nil
+		} else if err != nil {
+			return nil, tableError(name, idx, err)
+		}
+		var c T
+		value := reflect.ValueOf(&c).Elem()
+		for col, text := range record {
+			field, ee := tableField(value, columns[col])
+			if ee == nil {
+				ee = setTableValue(field, text)
+			}
+			if ee != nil {
+				line, _ := reader.FieldPos(col)
+				return nil, tableError(name, idx, fmt.Errorf("line %d: column %q: %v", line, header[col], ee))
+			}
+		}
+		cases = append(cases, c)
+	}
+}
+
+// tableField returns the field of the struct value at the index, allocating
+// any nil embedded struct pointers the field is promoted through
+func tableField(value reflect.Value, index []int) (field reflect.Value, err error) {
+	field = value
+	for idx, fieldIdx := range index {
+		if idx > 0 && field.Kind() == reflect.Pointer {
+			if field.IsNil() {
+				if !field.CanSet() {
+					err = fmt.Errorf("cannot set embedded pointer to unexported struct %v", field.Type().Elem())
+					return
+				}
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			field = field.Elem()
+		}
+		field = field.Field(fieldIdx)
+	}
+	return
+}
+
+func tagName(tag string) (name string) {
+	name, _, _ = strings.Cut(tag, ",")
+	if name == "-" {
+		name = ""
+	}
+	return
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// setTableValue converts the CSV text to the type of the field value
+func setTableValue(value reflect.Value, text string) (err error) {
+	if text == "" {
+		return
+	}
+	if unmarshaler, ok := value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(text))
+	}
+
+	switch {
+	case value.Type() == durationType:
+		var d time.Duration
+		if d, err = time.ParseDuration(text); err == nil {
+			value.SetInt(int64(d))
+		}
+	case value.Kind() == reflect.String:
+		value.SetString(text)
+	case value.Kind() == reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(text); err == nil {
+			value.SetBool(b)
+		}
+	case value.CanInt():
+		var i int64
+		if i, err = strconv.ParseInt(text, 10, value.Type().Bits()); err == nil {
+			value.SetInt(i)
+		}
+	case value.CanUint():
+		var u uint64
+		if u, err = strconv.ParseUint(text, 10, value.Type().Bits()); err == nil {
+			value.SetUint(u)
+		}
+	case value.CanFloat():
+		var f float64
+		if f, err = strconv.ParseFloat(text, value.Type().Bits()); err == nil {
+			value.SetFloat(f)
+		}
+	default:
+		err = fmt.Errorf("unsupported field type %v", value.Type())
+	}
+	return
+}
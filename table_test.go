@@ -0,0 +1,185 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type tableCase struct {
+	Name    string        `json:"name"`
+	Input   string        `json:"input"`
+	Want    int           `json:"want" csv:"expected"`
+	Ratio   float64       `json:"ratio"`
+	Enabled bool          `json:"enabled"`
+	Timeout time.Duration `json:"timeout"`
+	Addr    netip.Addr    `json:"addr"`
+}
+
+func (c *tableCase) Validate() (err error) {
+	if c.Want < 0 {
+		err = errors.New("want must not be negative")
+	}
+	return
+}
+
+type TableBase struct {
+	Name  string
+	Count uint
+}
+
+type tableUnexported struct {
+	Note string
+}
+
+type embeddedCase struct {
+	*TableBase
+	*tableUnexported
+	Input int
+}
+
+type taggedCase struct {
+	Title string `tdata:"name"`
+	Name  string
+}
+
+func TestTable(t *testing.T) {
+
+	Convey("Cases", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"table.json": File(`[
+				{"name": "empty", "input": "", "want": 0},
+				{"name": "word", "input": "abc", "want": 3, "ratio": 0.5, "enabled": true, "timeout": 1000000000, "addr": "10.0.0.1"}
+			]`),
+			"table.csv": File("" +
+				"name,input,expected,ratio,enabled,timeout,addr\n" +
+				"empty,,0,,,,\n" +
+				"word,abc,3,0.5,true,1s,10.0.0.1\n"),
+			"unknown.json":   File(`[{"name": "a"}, {"name": "b", "wnat": 1}]`),
+			"noname.json":    File(`[{"name": "a"}, {"input": "x"}]`),
+			"duplicate.json": File(`[{"name": "a"}, {"name": "b"}, {"name": "a"}]`),
+			"invalid.json":   File(`[{"name": "a", "want": -1}]`),
+			"object.json":    File(`{"name": "a"}`),
+			"column.csv":     File("name,bogus\na,b\n"),
+			"value.csv":      File("name,expected\na,1\nb,two\n"),
+			"table.yaml":     File("- name: a\n"),
+			"tagged.json":    File(`[{"Title": "first", "Name": "ignored"}]`),
+		}.Apply(tmpd), ShouldBeNil)
+
+		want := []tableCase{
+			{Name: "empty"},
+			{Name: "word", Input: "abc", Want: 3, Ratio: 0.5, Enabled: true, Timeout: time.Second, Addr: netip.MustParseAddr("10.0.0.1")},
+		}
+		cases, err := Cases[tableCase](tmpd, "table.json")
+		So(err, ShouldBeNil)
+		So(cases, ShouldResemble, want)
+		cases, err = Cases[tableCase](tmpd, "table.csv")
+		So(err, ShouldBeNil)
+		So(cases, ShouldResemble, want)
+
+		tagged, err := Cases[taggedCase](tmpd, "tagged.json")
+		So(err, ShouldBeNil)
+		So(tagged, ShouldResemble, []taggedCase{{Title: "first", Name: "ignored"}})
+
+		for file, message := range map[string]string{
+			"unknown.json":   `invalid case: unknown.json record 1: json: unknown field "wnat"`,
+			"noname.json":    `invalid case: noname.json record 1: missing name`,
+			"duplicate.json": `invalid case: duplicate.json record 2: duplicate name "a", first used by record 0`,
+			"invalid.json":   `invalid case: invalid.json record 0: want must not be negative`,
+			"column.csv":     `invalid case: column.csv: unknown column "bogus"`,
+			"value.csv":      `invalid case: value.csv record 1: line 3: column "expected": strconv.ParseInt: parsing "two": invalid syntax`,
+		} {
+			_, err = Cases[tableCase](tmpd, file)
+			So(errors.Is(err, ErrCaseInvalid), ShouldBeTrue)
+			So(err.Error(), ShouldEqual, message)
+		}
+
+		// integers are decimal, without prefixes
+		So(Tree{
+			"decimal.csv": File("name,expected\na,010\n"),
+			"hex.csv":     File("name,expected\na,0x10\n"),
+		}.Apply(tmpd), ShouldBeNil)
+		cases, err = Cases[tableCase](tmpd, "decimal.csv")
+		So(err, ShouldBeNil)
+		So(cases, ShouldResemble, []tableCase{{Name: "a", Want: 10}})
+		_, err = Cases[tableCase](tmpd, "hex.csv")
+		So(errors.Is(err, ErrCaseInvalid), ShouldBeTrue)
+		So(err.Error(), ShouldEqual, `invalid case: hex.csv record 0: line 2: column "expected": strconv.ParseInt: parsing "0x10": invalid syntax`)
+
+		_, err = Cases[tableCase](tmpd, "object.json")
+		So(errors.Is(err, ErrCaseInvalid), ShouldBeTrue)
+		_, err = Cases[tableCase](tmpd, "table.yaml")
+		So(errors.Is(err, ErrCasesFormat), ShouldBeTrue)
+		_, err = Cases[tableCase](tmpd, "missing.json")
+		So(err, ShouldNotBeNil)
+		_, err = Cases[struct{ Title string }](tmpd, "table.json")
+		So(errors.Is(err, ErrCaseInvalid), ShouldBeTrue)
+		_, err = Cases[string](tmpd, "table.json")
+		So(errors.Is(err, ErrCaseInvalid), ShouldBeTrue)
+	})
+
+	Convey("Embedded Pointers", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"embedded.csv": File("name,count,input\nfirst,2,3\n"),
+			"input.csv":    File("input\n1\n"),
+			"input.json":   File(`[{"Input": 1}]`),
+			"note.csv":     File("name,note\nfirst,x\n"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		cases, err := Cases[embeddedCase](tmpd, "embedded.csv")
+		So(err, ShouldBeNil)
+		So(cases, ShouldResemble, []embeddedCase{{TableBase: &TableBase{Name: "first", Count: 2}, Input: 3}})
+
+		// the name promoted through a nil pointer is missing
+		_, err = Cases[embeddedCase](tmpd, "input.csv")
+		So(err.Error(), ShouldEqual, `invalid case: input.csv record 0: missing name`)
+		_, err = Cases[embeddedCase](tmpd, "input.json")
+		So(err.Error(), ShouldEqual, `invalid case: input.json record 0: missing name`)
+
+		_, err = Cases[embeddedCase](tmpd, "note.csv")
+		So(errors.Is(err, ErrCaseInvalid), ShouldBeTrue)
+		So(err.Error(), ShouldEqual, `invalid case: note.csv record 0: line 2: column "note": cannot set embedded pointer to unexported struct tdata.tableUnexported`)
+	})
+
+	Convey("RunCases", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"table.csv": File("name,input,expected\nfirst,a,1\nsecond,bb,2\n"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		var names []string
+		var lengths []int
+		RunCases(t, tmpd, "table.csv", func(t *testing.T, c tableCase) {
+			names = append(names, t.Name())
+			lengths = append(lengths, len(c.Input)-c.Want)
+		})
+		So(names, ShouldEqual, []string{"TestTable/first", "TestTable/second"})
+		So(lengths, ShouldEqual, []int{0, 0})
+	})
+
+}
@@ -18,11 +18,16 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"testing"
 
 	clPath "github.com/go-corelibs/path"
 )
 
-var _ TData = (*tdata)(nil)
+var (
+	_ TData      = (*tdata)(nil)
+	_ FileOpener = (*tdata)(nil)
+	_ CaseRunner = (*tdata)(nil)
+)
 
 // TData is the filesystem interface common to both TestData and TempData
 // implementations
@@ -57,6 +62,22 @@ type TData interface {
 	LADH(dirname string) (found []string)
 }
 
+// FileOpener is implemented by the TestData, TempData and Case of this
+// package, separately from TData so that adding it did not break other
+// implementations of TData
+type FileOpener interface {
+	// Open opens the given file for reading
+	Open(filename string) (file *os.File, err error)
+}
+
+// CaseRunner is the Run method of the TestData, TempData and Case of this
+// package, which like FileOpener is not required of TData implementations
+type CaseRunner interface {
+	// Run runs fn as a named subtest for each case directory matching the
+	// pattern given
+	Run(t *testing.T, pattern string, fn func(t *testing.T, c Case))
+}
+
 type tdata struct {
 	path string
 }
@@ -86,7 +107,10 @@ func (td *tdata) Join(names ...string) (joined string) {
 	for _, name := range names {
 		join = append(join, td.prune(name))
 	}
-	return filepath.Join(join...)
+	joined = filepath.Join(join...)
+	// every path accessed, listed or returned passes through Join
+	trackUsage(joined)
+	return
 }
 
 func (td *tdata) E(filename string) (exists bool) {
@@ -101,6 +125,10 @@ func (td *tdata) F(filename string) (contents string) {
 	return
 }
 
+func (td *tdata) Open(filename string) (file *os.File, err error) {
+	return os.Open(td.Join(filename))
+}
+
 func (td *tdata) L(dirname string) (found []string) {
 	found, _ = clPath.List(td.Join(dirname), false)
 	td.cleanSlice(found)
@@ -36,6 +36,8 @@ type TempData interface {
 	Destroy() (err error)
 
 	TData
+	FileOpener
+	CaseRunner
 }
 
 // NewTempData constructs a new TempData instance using the given `dir` and
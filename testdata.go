@@ -17,10 +17,8 @@ package tdata
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"runtime"
-	"strings"
 
 	clPath "github.com/go-corelibs/path"
 )
@@ -40,6 +38,8 @@ type TestData interface {
 	Name() (name string)
 
 	TData
+	FileOpener
+	CaseRunner
 }
 
 type testdata struct {
@@ -58,7 +58,11 @@ func New() TestData {
 // runtime.Caller filename to find the correct package source directory, finds
 // the `go.mod` file indicating the top-level of the Go package and then looks
 // for the specified directory there. NewNamed will panic if the runtime.Caller
-// response is not ok or if the test data directory is not found
+// response is not ok or if the test data directory is not found. When the
+// test data directory has a ChecksumsFile, the first NewNamed call for the
+// directory verifies it with VerifyChecksums and any problems found fail the
+// tests using the directory with the tdata helpers, such as Golden, along
+// with the run when using Main
 func NewNamed(custom string) TestData {
 	return newTestData(1, custom)
 }
@@ -70,26 +74,66 @@ func newTestData(depth int, custom string) (td *testdata) {
 		panic(ErrRuntimeCaller)
 	}
 	td = &testdata{name: custom}
+
+	var err error
+	if td.path, err = findTestData(filepath.Dir(fn), custom); err != nil {
+		panic(err)
+	}
+	// problems are reported by the helpers given a testing.TB and by Main
+	_ = verifyChecksumsOnce(td.path)
+	registerUsageRoot(td.path)
+	guardRoot(td.path)
+	return
+}
+
+// Load constructs a new TestData instance for the Go package in the dirname
+// given, finding the custom test data directory the same way as NewNamed
+// does for the source file calling it. Load is intended for tooling and
+// returns errors instead of panicking, does not verify checksums and does
+// not participate in usage tracking or the integrity guard
+func Load(dirname, custom string) (td TestData, err error) {
+	var path string
+	if dirname, err = filepath.Abs(dirname); err != nil {
+		return
+	} else if path, err = findTestData(dirname, custom); err != nil {
+		return
+	}
+	t := &testdata{name: custom}
+	t.path = path
+	td = t
+	return
+}
+
+// findTestData returns the custom (or DefaultTestData) directory path in the
+// top-level of the Go module containing the dirname given
+func findTestData(dirname, custom string) (path string, err error) {
 	if custom == "" {
 		custom = DefaultTestData
 	}
-
-	dirnames := strings.Split(filepath.Dir(fn), string(os.PathSeparator))
-	for i := len(dirnames) - 1; i >= 0; i-- {
-		check := "/" + filepath.Join(dirnames[:i+1]...)
-		tdpath := filepath.Join(check, custom)
-		if stopped := clPath.IsFile(filepath.Join(check, "go.mod")); stopped {
-			if present := clPath.IsDir(tdpath); present {
-				td.path = tdpath
-				return
-			}
-			break
+	var root string
+	if root, err = findModuleRoot(dirname); err == nil {
+		if path = filepath.Join(root, custom); clPath.IsDir(path) {
+			return
 		}
 	}
-
-	panic(fmt.Errorf("%w: %s", ErrNotFound, custom))
+	return "", fmt.Errorf("%w: %s", ErrNotFound, custom)
 }
 
 func (td *testdata) Name() (name string) {
 	return td.name
 }
+
+// findModuleRoot returns the closest directory, starting with the path given
+// and working upwards, which contains a `go.mod` file
+func findModuleRoot(path string) (root string, err error) {
+	for check := filepath.Clean(path); ; check = filepath.Dir(check) {
+		if clPath.IsFile(filepath.Join(check, "go.mod")) {
+			root = check
+			return
+		} else if parent := filepath.Dir(check); parent == check {
+			break
+		}
+	}
+	err = fmt.Errorf("%w: module root of %s", ErrNotFound, path)
+	return
+}
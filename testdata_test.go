@@ -15,6 +15,7 @@
 package tdata
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"runtime"
@@ -100,4 +101,20 @@ func TestTestData(t *testing.T) {
 
 	})
 
+	Convey("Load", t, func() {
+		td, err := Load(filepath.Join(filepath.Dir(src), "pkg", "pkgtest"), "")
+		So(err, ShouldBeNil)
+		So(td.Path(), ShouldEqual, tdPath)
+		So(td.Name(), ShouldEqual, "")
+		td, err = Load(filepath.Dir(src), "_testdata")
+		So(err, ShouldBeNil)
+		So(td.Path(), ShouldEqual, _tdPath)
+		So(td.Name(), ShouldEqual, "_testdata")
+
+		_, err = Load(filepath.Dir(src), "not-a-thing")
+		So(errors.Is(err, ErrNotFound), ShouldBeTrue)
+		_, err = Load("/", "")
+		So(errors.Is(err, ErrNotFound), ShouldBeTrue)
+	})
+
 }
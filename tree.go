@@ -0,0 +1,419 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	clPath "github.com/go-corelibs/path"
+)
+
+const (
+	// DefaultFileMode is the permission used for Tree files without a Mode
+	DefaultFileMode fs.FileMode = 0644
+	// DefaultDirMode is the permission used for Tree directories without a
+	// Mode
+	DefaultDirMode fs.FileMode = 0755
+)
+
+// EntryType describes the kind of filesystem entry a TreeEntry represents
+type EntryType uint8
+
+const (
+	TypeUnknown EntryType = iota
+	TypeFile
+	TypeDir
+	TypeSymlink
+)
+
+func (et EntryType) String() string {
+	switch et {
+	case TypeFile:
+		return "file"
+	case TypeDir:
+		return "dir"
+	case TypeSymlink:
+		return "symlink"
+	}
+	return "unknown"
+}
+
+// TreeEntry describes a single file, directory or symlink within a Tree
+type TreeEntry struct {
+	// Type is the kind of entry
+	Type EntryType
+	// Content is the contents of a TypeFile entry
+	Content string
+	// Target is the link target of a TypeSymlink entry
+	Target string
+	// Mode is the permission bits to apply, zero uses DefaultFileMode or
+	// DefaultDirMode (and is ignored for symlinks)
+	Mode fs.FileMode
+	// ModTime is the modification time to apply, the zero value leaves the
+	// modification time as-is (and is ignored for symlinks)
+	ModTime time.Time
+}
+
+// File returns a TypeFile TreeEntry with the given contents
+func File(content string) TreeEntry {
+	return TreeEntry{Type: TypeFile, Content: content}
+}
+
+// Dir returns a TypeDir TreeEntry, only needed for empty directories or
+// directories requiring a specific Mode or ModTime as parent directories are
+// implied by the paths of their children
+func Dir() TreeEntry {
+	return TreeEntry{Type: TypeDir}
+}
+
+// Symlink returns a TypeSymlink TreeEntry pointing at the target given
+func Symlink(target string) TreeEntry {
+	return TreeEntry{Type: TypeSymlink, Target: target}
+}
+
+// WithMode returns a copy of the TreeEntry with the given Mode
+func (e TreeEntry) WithMode(mode fs.FileMode) TreeEntry {
+	e.Mode = mode.Perm()
+	return e
+}
+
+// WithModTime returns a copy of the TreeEntry with the given ModTime
+func (e TreeEntry) WithModTime(modTime time.Time) TreeEntry {
+	e.ModTime = modTime
+	return e
+}
+
+func (e TreeEntry) perm() (mode fs.FileMode) {
+	if mode = e.Mode.Perm(); mode == 0 {
+		if e.Type == TypeDir {
+			mode = DefaultDirMode
+		} else {
+			mode = DefaultFileMode
+		}
+	}
+	return
+}
+
+// Tree is a declarative description of a directory layout. Keys are
+// slash-separated paths relative to the root of the TData the Tree is applied
+// to or verified against and any parent directories not present as keys are
+// implied
+//
+// Example:
+//
+//	tree := tdata.Tree{
+//	  "go.mod":     tdata.File("module example\n"),
+//	  "bin/run.sh": tdata.File("#!/bin/sh\n").WithMode(0755),
+//	  "cache":      tdata.Dir().WithMode(0700),
+//	  "current":    tdata.Symlink("bin"),
+//	}
+type Tree map[string]TreeEntry
+
+// normalize returns a copy of the Tree with cleaned paths and all implied
+// parent directories added
+func (t Tree) normalize() (normal Tree, err error) {
+	normal = make(Tree)
+	for name, entry := range t {
+		cleaned := path.Clean(strings.TrimPrefix(filepath.ToSlash(name), "/"))
+		if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			err = fmt.Errorf("%w: %q", ErrTreePath, name)
+			return
+		}
+		switch entry.Type {
+		case TypeFile, TypeDir, TypeSymlink:
+		default:
+			err = fmt.Errorf("%w: %q has an unknown entry type", ErrTreePath, name)
+			return
+		}
+		if existing, present := normal[cleaned]; present && existing != entry {
+			err = fmt.Errorf("%w: %q is specified more than once", ErrTreePath, name)
+			return
+		}
+		normal[cleaned] = entry
+	}
+	for name := range normal {
+		for parent := path.Dir(name); parent != "."; parent = path.Dir(parent) {
+			if entry, present := normal[parent]; !present {
+				normal[parent] = Dir()
+			} else if entry.Type != TypeDir {
+				err = fmt.Errorf("%w: %q is not a directory", ErrTreePath, parent)
+				return
+			}
+		}
+	}
+	return
+}
+
+// Paths returns the sorted list of all paths in the Tree, including any
+// implied parent directories
+func (t Tree) Paths() (paths []string) {
+	normal, _ := t.normalize()
+	return normal.sorted()
+}
+
+func (t Tree) sorted() (paths []string) {
+	for name := range t {
+		paths = append(paths, name)
+	}
+	sort.Strings(paths)
+	return
+}
+
+// treeChtimes is os.Chtimes, replaced by the tests to force Apply to fail
+// after entries were moved into place
+var treeChtimes = os.Chtimes
+
+// Apply materializes the Tree within the given TempData. The entire layout is
+// first built within a hidden staging directory and only once that succeeds
+// are the new entries renamed into place. Should renaming them or applying
+// their permissions and times fail, the entries already moved into place are
+// removed again and the existing directories merged with have their mode and
+// modification time restored, so a failed Apply leaves the TempData as it
+// was, short of the rollback itself failing. Existing directories are merged
+// with, any other existing path specified by the Tree is an ErrTreeConflict
+func (t Tree) Apply(td TempData) (err error) {
+	var normal Tree
+	if normal, err = t.normalize(); err != nil {
+		return
+	}
+	paths := normal.sorted()
+
+	for _, name := range paths {
+		if info, ee := os.Lstat(td.Join(name)); ee == nil {
+			if !info.IsDir() || normal[name].Type != TypeDir {
+				err = fmt.Errorf("%w: %q already exists", ErrTreeConflict, name)
+				return
+			}
+		}
+	}
+
+	var staging string
+	if staging, err = os.MkdirTemp(td.Path(), ".tdata-tree-*"); err != nil {
+		return
+	}
+	defer func() {
+		_ = os.RemoveAll(staging)
+	}()
+
+	for _, name := range paths {
+		entry := normal[name]
+		target := filepath.Join(staging, filepath.FromSlash(name))
+		switch entry.Type {
+		case TypeDir:
+			err = os.Mkdir(target, 0700)
+		case TypeFile:
+			err = os.WriteFile(target, []byte(entry.Content), 0600)
+		case TypeSymlink:
+			err = os.Symlink(entry.Target, target)
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	moved := make(map[string]struct{})
+	merged := make(map[string]fs.FileInfo)
+	// renamed are the entries moved into place, without their children
+	var renamed []string
+	defer func() {
+		if err != nil {
+			rollbackApply(td, renamed, merged)
+		}
+	}()
+	for _, name := range paths {
+		if parent := path.Dir(name); parent != "." {
+			if _, present := moved[parent]; present {
+				moved[name] = struct{}{}
+				continue
+			}
+		}
+		if info, ee := os.Lstat(td.Join(name)); ee == nil {
+			// an existing directory being merged with
+			merged[name] = info
+			continue
+		}
+		if err = os.Rename(filepath.Join(staging, filepath.FromSlash(name)), td.Join(name)); err != nil {
+			return
+		}
+		moved[name] = struct{}{}
+		renamed = append(renamed, name)
+	}
+
+	// permissions and times are applied deepest first so that read-only
+	// directories and directory modification times are not disturbed by the
+	// changes to their children
+	for idx := len(paths) - 1; idx >= 0; idx-- {
+		entry := normal[paths[idx]]
+		if entry.Type == TypeSymlink {
+			continue
+		} else if _, present := merged[paths[idx]]; present && entry.Mode == 0 && entry.ModTime.IsZero() {
+			// existing directories are left alone unless explicitly specified
+			continue
+		}
+		target := td.Join(paths[idx])
+		if _, present := merged[paths[idx]]; !present || entry.Mode != 0 {
+			if err = os.Chmod(target, entry.perm()); err != nil {
+				return
+			}
+		}
+		if !entry.ModTime.IsZero() {
+			if err = treeChtimes(target, entry.ModTime, entry.ModTime); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// rollbackApply undoes a failed Apply, removing the entries renamed into
+// place and restoring the mode and modification time of the existing
+// directories which were merged with
+func rollbackApply(td TempData, renamed []string, merged map[string]fs.FileInfo) {
+	for name := range merged {
+		_ = os.Chmod(td.Join(name), 0700)
+	}
+	for idx := len(renamed) - 1; idx >= 0; idx-- {
+		target := td.Join(renamed[idx])
+		if info, err := os.Lstat(target); err == nil && info.IsDir() {
+			_ = clPath.ChmodAll(target)
+		}
+		_ = os.RemoveAll(target)
+	}
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+	// deepest first, as with Apply
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	for _, name := range names {
+		info := merged[name]
+		_ = os.Chmod(td.Join(name), info.Mode().Perm())
+		_ = os.Chtimes(td.Join(name), info.ModTime(), info.ModTime())
+	}
+}
+
+// Verify checks that the given TData matches the Tree exactly. Every entry
+// in the Tree must be present with the same type, content, symlink target
+// and, when specified, the same Mode and ModTime (to the second). Any paths
+// present in the TData and not in the Tree are also reported. The error
+// returned wraps ErrTreeMismatch and lists each problem found
+func (t Tree) Verify(td TData) (err error) {
+	var normal Tree
+	if normal, err = t.normalize(); err != nil {
+		return
+	}
+
+	var problems []string
+	for _, name := range normal.sorted() {
+		if problem := normal[name].verify(td.Join(name)); problem != "" {
+			problems = append(problems, name+": "+problem)
+		}
+	}
+
+	root := td.Path()
+	if err = filepath.WalkDir(root, func(p string, d fs.DirEntry, ee error) error {
+		if ee != nil {
+			return ee
+		} else if p == root {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		if entry, present := normal[rel]; !present {
+			problems = append(problems, rel+": unexpected")
+		} else if entry.Type == TypeDir {
+			return nil
+		}
+		if d.IsDir() {
+			// nothing within is expected
+			return filepath.SkipDir
+		}
+		return nil
+	}); err != nil {
+		return
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		err = fmt.Errorf("%w:\n%s", ErrTreeMismatch, strings.Join(problems, "\n"))
+	}
+	return
+}
+
+func (e TreeEntry) verify(target string) (problem string) {
+	info, err := os.Lstat(target)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "missing"
+		}
+		return err.Error()
+	}
+
+	if found := entryTypeOf(info.Mode()); found != e.Type {
+		return fmt.Sprintf("expected %s, found %s", e.Type, found)
+	}
+
+	switch e.Type {
+	case TypeSymlink:
+		if link, ee := os.Readlink(target); ee != nil {
+			return ee.Error()
+		} else if link != e.Target {
+			return fmt.Sprintf("expected target %q, found %q", e.Target, link)
+		}
+		return
+	case TypeFile:
+		if data, ee := os.ReadFile(target); ee != nil {
+			return ee.Error()
+		} else if string(data) != e.Content {
+			if isText(data) && isText([]byte(e.Content)) {
+				return "content differs\n" + strings.TrimSuffix(textDiff(e.Content, string(data)), "\n")
+			}
+			return "content differs"
+		}
+	}
+
+	if e.Mode != 0 && info.Mode().Perm() != e.Mode.Perm() {
+		return fmt.Sprintf("expected mode %v, found %v", e.Mode.Perm(), info.Mode().Perm())
+	}
+	if !e.ModTime.IsZero() && info.ModTime().Unix() != e.ModTime.Unix() {
+		return fmt.Sprintf("expected mtime %v, found %v", e.ModTime.UTC(), info.ModTime().UTC())
+	}
+	return
+}
+
+func entryTypeOf(mode fs.FileMode) (et EntryType) {
+	switch {
+	case mode.IsRegular():
+		return TypeFile
+	case mode.IsDir():
+		return TypeDir
+	case mode&fs.ModeSymlink != 0:
+		return TypeSymlink
+	}
+	return TypeUnknown
+}
+
+func lexists(path string) (exists bool) {
+	_, err := os.Lstat(path)
+	return err == nil
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTree(t *testing.T) {
+
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	Convey("Apply", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		tree := Tree{
+			"file.txt":      File("contents\n"),
+			"bin/run.sh":    File("#!/bin/sh\n").WithMode(0755),
+			"empty":         Dir().WithModTime(mtime),
+			"locked":        Dir().WithMode(0500),
+			"locked/inside": File("ro").WithMode(0400).WithModTime(mtime),
+			"current":       Symlink("bin"),
+		}
+		So(tree.Paths(), ShouldEqual, []string{
+			"bin", "bin/run.sh", "current", "empty", "file.txt", "locked", "locked/inside",
+		})
+		So(tree.Apply(tmpd), ShouldBeNil)
+		So(tmpd.F("file.txt"), ShouldEqual, "contents\n")
+		So(tmpd.F("locked/inside"), ShouldEqual, "ro")
+		So(tmpd.LH("."), ShouldEqual, []string{
+			tmpd.Join("bin"),
+			tmpd.Join("empty"),
+			tmpd.Join("locked"),
+			tmpd.Join("current"),
+			tmpd.Join("file.txt"),
+		})
+
+		info, err := os.Stat(tmpd.Join("bin/run.sh"))
+		So(err, ShouldBeNil)
+		So(info.Mode().Perm(), ShouldEqual, os.FileMode(0755))
+		info, err = os.Stat(tmpd.Join("locked"))
+		So(err, ShouldBeNil)
+		So(info.Mode().Perm(), ShouldEqual, os.FileMode(0500))
+		info, err = os.Stat(tmpd.Join("empty"))
+		So(err, ShouldBeNil)
+		So(info.ModTime().Equal(mtime), ShouldBeTrue)
+		link, err := os.Readlink(tmpd.Join("current"))
+		So(err, ShouldBeNil)
+		So(link, ShouldEqual, "bin")
+
+		So(tree.Verify(tmpd), ShouldBeNil)
+
+		Convey("merging with existing directories", func() {
+			So(Tree{"bin/other": File("other")}.Apply(tmpd), ShouldBeNil)
+			So(tmpd.F("bin/other"), ShouldEqual, "other")
+			So(tmpd.F("bin/run.sh"), ShouldEqual, "#!/bin/sh\n")
+		})
+	})
+
+	Convey("Apply Errors", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(os.WriteFile(tmpd.Join("taken"), []byte("taken"), 0644), ShouldBeNil)
+
+		err = Tree{"../escape": File("")}.Apply(tmpd)
+		So(errors.Is(err, ErrTreePath), ShouldBeTrue)
+		err = Tree{"file/child": File(""), "file": File("")}.Apply(tmpd)
+		So(errors.Is(err, ErrTreePath), ShouldBeTrue)
+		err = Tree{"unknown": {}}.Apply(tmpd)
+		So(errors.Is(err, ErrTreePath), ShouldBeTrue)
+
+		err = Tree{"new/file.txt": File("new"), "taken": File("")}.Apply(tmpd)
+		So(errors.Is(err, ErrTreeConflict), ShouldBeTrue)
+		// nothing was changed by the failed Apply
+		So(tmpd.LAH("."), ShouldEqual, []string{tmpd.Join("taken")})
+	})
+
+	Convey("Apply Rollback", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{"existing/kept.txt": File("kept")}.Apply(tmpd), ShouldBeNil)
+		mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		So(os.Chtimes(tmpd.Join("existing"), mtime, mtime), ShouldBeNil)
+		before, err := os.Stat(tmpd.Join("existing"))
+		So(err, ShouldBeNil)
+
+		defer func(previous func(string, time.Time, time.Time) error) { treeChtimes = previous }(treeChtimes)
+		treeChtimes = func(name string, atime, mtime time.Time) (err error) {
+			return errors.New("forced failure")
+		}
+		err = Tree{
+			"existing/added.txt": File("added"),
+			"locked":             Dir().WithMode(0500),
+			"locked/file.txt":    File("file"),
+			"stamped.txt":        File("stamped").WithModTime(mtime),
+		}.Apply(tmpd)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "forced failure")
+
+		// the entries moved into place were removed again
+		So(tmpd.LAH("."), ShouldEqual, []string{tmpd.Join("existing"), tmpd.Join("existing/kept.txt")})
+		info, err := os.Stat(tmpd.Join("existing"))
+		So(err, ShouldBeNil)
+		So(info.ModTime().Equal(mtime), ShouldBeTrue)
+		So(info.Mode().Perm(), ShouldEqual, before.Mode().Perm())
+	})
+
+	Convey("Verify", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		So(Tree{
+			"a.txt":   File("a"),
+			"b.txt":   File("b").WithMode(0600),
+			"c":       Symlink("a.txt"),
+			"d/e.txt": File("e"),
+			"extra":   File("extra"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		err = Tree{
+			"a.txt":   File("changed"),
+			"b.txt":   File("b").WithMode(0644),
+			"c":       Symlink("b.txt"),
+			"d":       File(""),
+			"missing": Dir(),
+		}.Verify(tmpd)
+		So(errors.Is(err, ErrTreeMismatch), ShouldBeTrue)
+		So(err.Error(), ShouldEqual, ErrTreeMismatch.Error()+":\n"+
+			"a.txt: content differs\n"+
+			"--- want\n"+
+			"+++ got\n"+
+			"@@ -1 +1 @@\n"+
+			"- 1   | changed\n"+
+			"\\ No newline at end of file\n"+
+			"+   1 | a\n"+
+			"\\ No newline at end of file\n"+
+			"b.txt: expected mode -rw-r--r--, found -rw-------\n"+
+			"c: expected target \"b.txt\", found \"a.txt\"\n"+
+			"d: expected file, found dir\n"+
+			"extra: unexpected\n"+
+			"missing: missing",
+		)
+	})
+
+}
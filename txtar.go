@@ -0,0 +1,210 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// TxtarExtension is the file extension of txtar archives
+const TxtarExtension = ".txtar"
+
+// ParseTxtar parses the txtar archive data, returning the leading comment and
+// a Tree of the files within. Each file starts with a "-- name --" marker
+// line and the file names must be relative paths within the archive
+func ParseTxtar(data []byte) (comment string, tree Tree, err error) {
+	tree = make(Tree)
+	var name string
+	var content bytes.Buffer
+	flush := func() {
+		if name == "" {
+			comment = content.String()
+		} else {
+			tree[name] = File(content.String())
+		}
+		content.Reset()
+	}
+
+	for lineNo, rest := 1, data; len(rest) > 0; lineNo++ {
+		line := rest
+		if idx := bytes.IndexByte(rest, '\n'); idx >= 0 {
+			line, rest = rest[:idx+1], rest[idx+1:]
+		} else {
+			rest = nil
+		}
+		marker, ok := txtarMarker(line)
+		if !ok {
+			content.Write(line)
+			continue
+		}
+		cleaned := path.Clean(marker)
+		if marker == "" || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") || path.IsAbs(marker) {
+			err = fmt.Errorf("%w: line %d: invalid file name %q", ErrTxtarSyntax, lineNo, marker)
+			return
+		} else if _, present := tree[cleaned]; present {
+			err = fmt.Errorf("%w: line %d: duplicate file name %q", ErrTxtarSyntax, lineNo, marker)
+			return
+		}
+		flush()
+		name = cleaned
+		tree[name] = File("")
+	}
+	flush()
+	return
+}
+
+// FormatTxtar returns the txtar archive of the comment and the files of the
+// tree, in lexical order. Directories are implied by the file names and are
+// otherwise omitted, symlinks are not supported. A newline is appended to any
+// content not already ending with one, as the txtar format requires
+func FormatTxtar(comment string, tree Tree) (data []byte, err error) {
+	var buf bytes.Buffer
+	buf.WriteString(txtarFixNewline(comment))
+	for _, name := range tree.sorted() {
+		entry := tree[name]
+		switch entry.Type {
+		case TypeDir:
+			continue
+		case TypeFile:
+		default:
+			err = fmt.Errorf("%w: %q is a %s", ErrTxtarSyntax, name, entry.Type)
+			return
+		}
+		for _, line := range strings.SplitAfter(entry.Content, "\n") {
+			if _, ok := txtarMarker([]byte(line)); ok {
+				err = fmt.Errorf("%w: %q contains a file marker line: %q", ErrTxtarSyntax, name, strings.TrimSpace(line))
+				return
+			}
+		}
+		buf.WriteString("-- " + filepath.ToSlash(name) + " --\n")
+		buf.WriteString(txtarFixNewline(entry.Content))
+	}
+	data = buf.Bytes()
+	return
+}
+
+// PackTxtar returns a txtar archive of all the files, including hidden files,
+// within the dirname of td, named relative to the dirname. So that
+// UnpackTxtar gives back the same bytes, binary files and files which do not
+// end with a newline are an ErrTxtarSyntax naming the file
+func PackTxtar(td TData, dirname string) (data []byte, err error) {
+	root := filepath.Join(td.Path(), dirname)
+	tree := make(Tree)
+	if err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		if !d.Type().IsRegular() {
+			return fmt.Errorf("%w: %q is not a regular file", ErrTxtarSyntax, filepath.ToSlash(rel))
+		}
+		content, ee := os.ReadFile(path)
+		trackUsage(path)
+		if ee != nil {
+			return ee
+		} else if !utf8.Valid(content) || bytes.IndexByte(content, 0) >= 0 {
+			return fmt.Errorf("%w: %q is a binary file", ErrTxtarSyntax, filepath.ToSlash(rel))
+		} else if len(content) > 0 && content[len(content)-1] != '\n' {
+			return fmt.Errorf("%w: %q does not end with a newline", ErrTxtarSyntax, filepath.ToSlash(rel))
+		}
+		tree[filepath.ToSlash(rel)] = File(string(content))
+		return nil
+	}); err != nil {
+		return
+	}
+	return FormatTxtar("", tree)
+}
+
+// UnpackTxtar writes the files of the txtar archive data into the dirname of
+// td, creating any directories needed. Existing files are an ErrTreeConflict
+// unless overwrite is true and a dirname or file which resolves to outside of
+// td, symbolic links included, is an ErrTreePath. The names of the files
+// written are returned, in lexical order and relative to td
+func UnpackTxtar(td TData, dirname string, data []byte, overwrite bool) (written []string, err error) {
+	var tree Tree
+	if _, tree, err = ParseTxtar(data); err != nil {
+		return
+	}
+	names := tree.sorted()
+	for _, name := range append([]string{""}, names...) {
+		if !withinRoot(td.Path(), filepath.Join(td.Path(), dirname, filepath.FromSlash(name))) {
+			err = fmt.Errorf("%w: %q is outside of %s", ErrTreePath, path.Join(filepath.ToSlash(dirname), name), td.Path())
+			return
+		}
+	}
+	if !overwrite {
+		for _, name := range names {
+			if lexists(filepath.Join(td.Path(), dirname, filepath.FromSlash(name))) {
+				err = fmt.Errorf("%w: %q already exists", ErrTreeConflict, path.Join(filepath.ToSlash(dirname), name))
+				return
+			}
+		}
+	}
+	for _, name := range names {
+		target := filepath.Join(td.Path(), dirname, filepath.FromSlash(name))
+		if err = writeFileAtomic(target, []byte(tree[name].Content), DefaultFileMode); err != nil {
+			return
+		}
+		written = append(written, path.Join(filepath.ToSlash(dirname), name))
+	}
+	return
+}
+
+// withinRoot returns true if the target path, with the symbolic links of the
+// parts which exist resolved, is the root or is within the root
+func withinRoot(root, target string) (within bool) {
+	var err error
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return false
+	}
+	var rest []string
+	for existing := filepath.Clean(target); ; {
+		if resolved, ee := filepath.EvalSymlinks(existing); ee == nil {
+			target = filepath.Join(append([]string{resolved}, rest...)...)
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return false
+		}
+		rest = append([]string{filepath.Base(existing)}, rest...)
+		existing = parent
+	}
+	rel, err := filepath.Rel(root, target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// txtarMarker returns the file name of a "-- name --" marker line
+func txtarMarker(line []byte) (name string, ok bool) {
+	text := strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r")
+	if strings.HasPrefix(text, "-- ") && strings.HasSuffix(text, " --") && len(text) >= 6 {
+		return strings.TrimSpace(text[3 : len(text)-3]), true
+	}
+	return
+}
+
+func txtarFixNewline(text string) (fixed string) {
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		return text + "\n"
+	}
+	return text
+}
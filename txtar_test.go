@@ -0,0 +1,175 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTxtar(t *testing.T) {
+
+	Convey("Parse and Format", t, func() {
+		archive := "" +
+			"a comment\n" +
+			"-- b.txt --\n" +
+			"bee\n" +
+			"-- a/nested.txt --\n" +
+			"nested\n" +
+			"\n" +
+			"-- empty --\n" +
+			"-- last.txt --\n" +
+			"no newline"
+		comment, tree, err := ParseTxtar([]byte(archive))
+		So(err, ShouldBeNil)
+		So(comment, ShouldEqual, "a comment\n")
+		So(tree, ShouldResemble, Tree{
+			"b.txt":        File("bee\n"),
+			"a/nested.txt": File("nested\n\n"),
+			"empty":        File(""),
+			"last.txt":     File("no newline"),
+		})
+
+		data, err := FormatTxtar(comment, tree)
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, ""+
+			"a comment\n"+
+			"-- a/nested.txt --\n"+
+			"nested\n"+
+			"\n"+
+			"-- b.txt --\n"+
+			"bee\n"+
+			"-- empty --\n"+
+			"-- last.txt --\n"+
+			"no newline\n",
+		)
+
+		comment, tree, err = ParseTxtar(nil)
+		So(err, ShouldBeNil)
+		So(comment, ShouldEqual, "")
+		So(tree, ShouldBeEmpty)
+
+		for _, archive = range []string{
+			"-- ../escape --\n",
+			"-- /absolute --\n",
+			"-- . --\n",
+			"-- a --\n-- ./a --\n",
+		} {
+			_, _, err = ParseTxtar([]byte(archive))
+			So(errors.Is(err, ErrTxtarSyntax), ShouldBeTrue)
+		}
+
+		_, err = FormatTxtar("", Tree{"marker.txt": File("-- inner --\n")})
+		So(errors.Is(err, ErrTxtarSyntax), ShouldBeTrue)
+		_, err = FormatTxtar("", Tree{"link": Symlink("target")})
+		So(errors.Is(err, ErrTxtarSyntax), ShouldBeTrue)
+		data, err = FormatTxtar("", Tree{"dir": Dir(), "dir/file": File("file\n")})
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "-- dir/file --\nfile\n")
+	})
+
+	Convey("Pack and Unpack", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"cases/one/input.txt":  File("one\n"),
+			"cases/one/.hidden":    File("hidden\n"),
+			"cases/two/input.txt":  File("two\n"),
+			"other/unrelated.txt":  File("unrelated\n"),
+			"links/link":           Symlink("../other"),
+			"links/link-free.file": File("free\n"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		data, err := PackTxtar(tmpd, "cases")
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, ""+
+			"-- one/.hidden --\n"+
+			"hidden\n"+
+			"-- one/input.txt --\n"+
+			"one\n"+
+			"-- two/input.txt --\n"+
+			"two\n",
+		)
+		_, err = PackTxtar(tmpd, "links")
+		So(errors.Is(err, ErrTxtarSyntax), ShouldBeTrue)
+		_, err = PackTxtar(tmpd, "missing")
+		So(err, ShouldNotBeNil)
+
+		written, err := UnpackTxtar(tmpd, "copy", data, false)
+		So(err, ShouldBeNil)
+		So(written, ShouldEqual, []string{"copy/one/.hidden", "copy/one/input.txt", "copy/two/input.txt"})
+		So(tmpd.F("copy/two/input.txt"), ShouldEqual, "two\n")
+
+		So(os.WriteFile(tmpd.Join("copy", "one", "input.txt"), []byte("changed\n"), 0644), ShouldBeNil)
+		_, err = UnpackTxtar(tmpd, "copy", data, false)
+		So(errors.Is(err, ErrTreeConflict), ShouldBeTrue)
+		So(err.Error(), ShouldEqual, `tree conflict: "copy/one/.hidden" already exists`)
+		So(tmpd.F("copy/one/input.txt"), ShouldEqual, "changed\n")
+		_, err = UnpackTxtar(tmpd, "copy", data, true)
+		So(err, ShouldBeNil)
+		So(tmpd.F("copy/one/input.txt"), ShouldEqual, "one\n")
+
+		written, err = UnpackTxtar(tmpd, "", []byte("-- top.txt --\ntop\n"), false)
+		So(err, ShouldBeNil)
+		So(written, ShouldEqual, []string{"top.txt"})
+		_, err = UnpackTxtar(tmpd, "", []byte("-- ../top.txt --\n"), false)
+		So(errors.Is(err, ErrTxtarSyntax), ShouldBeTrue)
+
+		// nothing is written outside of the td
+		for _, dirname := range []string{"..", "../escaped", "copy/../.."} {
+			_, err = UnpackTxtar(tmpd, dirname, []byte("-- escaped.txt --\nescaped\n"), true)
+			So(errors.Is(err, ErrTreePath), ShouldBeTrue)
+		}
+		So(os.Symlink(filepath.Dir(tmpd.Path()), tmpd.Join("copy", "out")), ShouldBeNil)
+		_, err = UnpackTxtar(tmpd, "copy", []byte("-- out/escaped.txt --\nescaped\n"), true)
+		So(errors.Is(err, ErrTreePath), ShouldBeTrue)
+		So(err.Error(), ShouldEqual, `invalid tree path: "copy/out/escaped.txt" is outside of `+tmpd.Path())
+		So(tmpd.E("other/escaped.txt"), ShouldBeFalse)
+		_, err = os.Lstat(filepath.Join(filepath.Dir(tmpd.Path()), "escaped.txt"))
+		So(os.IsNotExist(err), ShouldBeTrue)
+	})
+
+	Convey("Pack Round Trip", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"text/one.txt":    File("one\n"),
+			"text/empty":      File(""),
+			"binary/data.bin": File("\x00\x01\xff\n"),
+			"partial/end.txt": File("no newline"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		data, err := PackTxtar(tmpd, "text")
+		So(err, ShouldBeNil)
+		_, err = UnpackTxtar(tmpd, "copy", data, false)
+		So(err, ShouldBeNil)
+		So(tmpd.F("copy/one.txt"), ShouldEqual, "one\n")
+		So(tmpd.F("copy/empty"), ShouldEqual, "")
+
+		_, err = PackTxtar(tmpd, "binary")
+		So(errors.Is(err, ErrTxtarSyntax), ShouldBeTrue)
+		So(err.Error(), ShouldEqual, `txtar syntax error: "data.bin" is a binary file`)
+		_, err = PackTxtar(tmpd, "partial")
+		So(errors.Is(err, ErrTxtarSyntax), ShouldBeTrue)
+		So(err.Error(), ShouldEqual, `txtar syntax error: "end.txt" does not end with a newline`)
+	})
+
+}
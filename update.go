@@ -0,0 +1,361 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	// UpdateFlag is the name of the command-line flag which enables updating
+	// golden files. Without a value, or with a true value, all golden files
+	// are updated. Any other value is a comma-separated list of patterns
+	// scoping the update to the golden files whose name, the name of the
+	// variant it resolves to, or the name of the test using them, matches one
+	// of the patterns. Patterns are path.Match globs, which also match
+	// everything below a matching directory or test, unless wrapped in
+	// slashes, as in `/parser_.*/`, for a regexp
+	UpdateFlag = "tdata.update"
+	// UpdateEnv is the name of the environment variable which enables
+	// updating golden files, useful when running `go test ./...` across
+	// packages which do not all import tdata (and so do not all accept the
+	// UpdateFlag). The value is the same as for the UpdateFlag
+	UpdateEnv = "TDATA_UPDATE"
+	// DryRunFlag is the name of the command-line flag which enables dry run
+	// mode, where golden files are updated as with the UpdateFlag except that
+	// nothing is written and the files which would change are listed instead
+	DryRunFlag = "tdata.dry-run"
+	// DryRunEnv is the environment variable equivalent of the DryRunFlag
+	DryRunEnv = "TDATA_DRY_RUN"
+)
+
+var (
+	updateGoldens = &updateValue{}
+	dryRunGoldens = flag.Bool(DryRunFlag, false, "list the tdata golden files which would be updated, without writing them")
+)
+
+func init() {
+	flag.Var(updateGoldens, UpdateFlag, "update tdata golden files, optionally only those matching the comma-separated globs or /regexps/ given")
+}
+
+// updateValue is the flag.Value of the UpdateFlag, which may be given
+// without a value like a flag.Bool
+type updateValue struct {
+	value string
+}
+
+func (v *updateValue) String() (value string) {
+	if v != nil {
+		value = v.value
+	}
+	return
+}
+
+func (v *updateValue) Set(value string) (err error) {
+	if _, err = parseUpdateScope(value); err == nil {
+		v.value = value
+	}
+	return
+}
+
+func (v *updateValue) IsBoolFlag() (ok bool) {
+	return true
+}
+
+// updateScope is the parsed value of the UpdateFlag or UpdateEnv
+type updateScope struct {
+	all      bool
+	globs    []string
+	patterns []*regexp.Regexp
+	// err is the problem with an invalid UpdateEnv, the UpdateFlag itself
+	// rejects invalid values
+	err error
+}
+
+// updateScopes caches the parsed updateScope of each value
+var updateScopes sync.Map
+
+// parseUpdateScope parses the value of the UpdateFlag or UpdateEnv, returning
+// a nil scope when updating is not enabled
+func parseUpdateScope(value string) (scope *updateScope, err error) {
+	if value = strings.TrimSpace(value); value == "" {
+		return
+	} else if enabled, ee := strconv.ParseBool(value); ee == nil {
+		if enabled {
+			scope = &updateScope{all: true}
+		}
+		return
+	}
+
+	scope = &updateScope{}
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		} else if len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+			var rx *regexp.Regexp
+			if rx, err = regexp.Compile(pattern[1 : len(pattern)-1]); err != nil {
+				return nil, fmt.Errorf("%w: %q: %v", ErrUpdateScope, pattern, err)
+			}
+			scope.patterns = append(scope.patterns, rx)
+		} else if _, err = path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrUpdateScope, pattern, err)
+		} else {
+			scope.globs = append(scope.globs, pattern)
+		}
+	}
+	if len(scope.globs) == 0 && len(scope.patterns) == 0 {
+		scope = nil
+	}
+	return
+}
+
+// currentUpdateScope returns the updateScope of the UpdateFlag, or of the
+// UpdateEnv when the flag is not set, with DryRun alone scoping all files
+func currentUpdateScope() (scope *updateScope) {
+	value := updateGoldens.value
+	if scope = cachedUpdateScope(value); scope == nil {
+		value = os.Getenv(UpdateEnv)
+		scope = cachedUpdateScope(value)
+	}
+	if scope == nil && DryRun() {
+		scope = &updateScope{all: true}
+	}
+	return
+}
+
+func cachedUpdateScope(value string) (scope *updateScope) {
+	if cached, ok := updateScopes.Load(value); ok {
+		return cached.(*updateScope)
+	}
+	var err error
+	if scope, err = parseUpdateScope(value); err != nil {
+		scope = &updateScope{err: err}
+	}
+	updateScopes.Store(value, scope)
+	return
+}
+
+// matches returns true if all files are in scope or if any of the patterns
+// match the golden file name or the test name
+func (s *updateScope) matches(name, testName string) (ok bool) {
+	if s.all {
+		return true
+	}
+	name = filepath.ToSlash(name)
+	for _, rx := range s.patterns {
+		if rx.MatchString(name) || rx.MatchString(testName) {
+			return true
+		}
+	}
+	for _, glob := range s.globs {
+		if matchPrefix(glob, name) || matchPrefix(glob, testName) {
+			return true
+		}
+	}
+	return
+}
+
+// matchPrefix returns true if the glob matches the value, or any leading
+// slash-separated part of the value
+func matchPrefix(glob, value string) (matched bool) {
+	for idx := 0; idx < len(value); idx++ {
+		if value[idx] == '/' {
+			if matched, _ = path.Match(glob, value[:idx]); matched {
+				return
+			}
+		}
+	}
+	matched, _ = path.Match(glob, value)
+	return
+}
+
+// Updating returns true if golden files are being updated instead of
+// compared, which is enabled with either the `-tdata.update` flag or the
+// TDATA_UPDATE environment variable set to a true value or to the patterns
+// of the golden files to update, see UpdateFlag. Updating is also enabled
+// by DryRun
+func Updating() (updating bool) {
+	return currentUpdateScope() != nil
+}
+
+// DryRun returns true if golden file updates are listed instead of written,
+// which is enabled with either the `-tdata.dry-run` flag or the
+// TDATA_DRY_RUN environment variable set to a true value
+func DryRun() (dryRun bool) {
+	if dryRun = *dryRunGoldens; !dryRun {
+		dryRun, _ = strconv.ParseBool(os.Getenv(DryRunEnv))
+	}
+	return
+}
+
+// updatingGolden returns true if any of the names of a golden file, such as
+// the name given and the variant it resolved to, used by the test t, is
+// within the scope of the current update, failing the test when the UpdateEnv
+// is not valid
+func updatingGolden(t testing.TB, names ...string) (updating bool) {
+	t.Helper()
+	scope := currentUpdateScope()
+	if scope == nil {
+		return false
+	} else if scope.err != nil {
+		t.Errorf("invalid %s: %v", UpdateEnv, scope.err)
+		return false
+	}
+	for _, name := range names {
+		if scope.matches(name, t.Name()) {
+			return true
+		}
+	}
+	return
+}
+
+// goldenUpdate is the content a golden file was updated with
+type goldenUpdate struct {
+	test string
+	sum  [sha256.Size]byte
+}
+
+var goldenUpdates = struct {
+	sync.Mutex
+	// files maps the path of each golden file updated to its update
+	files map[string]goldenUpdate
+}{
+	files: make(map[string]goldenUpdate),
+}
+
+// claimGoldenUpdate records data as the update of the golden file at the path
+// given, returning an ErrGoldenConflict when the file was already updated
+// with different data, typically by another parallel test
+func claimGoldenUpdate(t testing.TB, filename string, data []byte) (err error) {
+	update := goldenUpdate{test: t.Name(), sum: sha256.Sum256(data)}
+	goldenUpdates.Lock()
+	defer goldenUpdates.Unlock()
+	if previous, present := goldenUpdates.files[filename]; !present {
+		goldenUpdates.files[filename] = update
+	} else if previous.sum != update.sum {
+		err = fmt.Errorf("%w: different content was written by %s", ErrGoldenConflict, previous.test)
+	}
+	return
+}
+
+// updateGolden writes data to the named golden file within td, and refreshes
+// its entry in any ChecksumsFile, unless this is a DryRun, where the change
+// which would have been made is noted instead and written is false. Updating
+// the same golden file more than once with different data is an
+// ErrGoldenConflict
+func updateGolden(t testing.TB, td TData, name string, data []byte) (written bool, err error) {
+	t.Helper()
+	if err = claimGoldenUpdate(t, td.Join(name), data); err != nil {
+		return
+	} else if DryRun() {
+		err = noteDryRun(t, td, name, data)
+		return
+	}
+	if err = writeGolden(td, name, data); err == nil {
+		written = true
+		err = refreshChecksums(td, name)
+	}
+	return
+}
+
+// dryRunChange is a golden file change noted during a DryRun
+type dryRunChange struct {
+	test    string
+	created bool
+	added   int
+	removed int
+}
+
+func (c dryRunChange) String() (text string) {
+	if c.created {
+		return fmt.Sprintf("new file, %d lines", c.added)
+	}
+	return fmt.Sprintf("+%d -%d lines", c.added, c.removed)
+}
+
+var dryRunChanges = struct {
+	sync.Mutex
+	// files maps the path of each golden file which would change
+	files map[string]dryRunChange
+}{
+	files: make(map[string]dryRunChange),
+}
+
+// noteDryRun compares data with the current contents of the named golden file
+// and, if they differ, notes the change and logs it to t
+func noteDryRun(t testing.TB, td TData, name string, data []byte) (err error) {
+	t.Helper()
+	change := dryRunChange{test: t.Name()}
+	var want []byte
+	if want, err = readGolden(td, name); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return
+		}
+		err = nil
+		change.created = true
+	} else if string(want) == string(data) {
+		return
+	}
+	for _, edit := range myers(splitLines(string(want)), splitLines(string(data))) {
+		switch edit.op {
+		case '+':
+			change.added++
+		case '-':
+			change.removed++
+		}
+	}
+
+	dryRunChanges.Lock()
+	dryRunChanges.files[td.Join(name)] = change
+	dryRunChanges.Unlock()
+	t.Logf("golden file would change: %s (%s)", name, change)
+	return
+}
+
+// writeDryRunSummary lists the golden files which would change, returning the
+// number of files listed
+func writeDryRunSummary(w io.Writer) (count int) {
+	dryRunChanges.Lock()
+	defer dryRunChanges.Unlock()
+	if count = len(dryRunChanges.files); count == 0 {
+		_, _ = fmt.Fprintf(w, "tdata: dry run, no golden files would change\n")
+		return
+	}
+	paths := make([]string, 0, count)
+	for abs := range dryRunChanges.files {
+		paths = append(paths, abs)
+	}
+	sort.Strings(paths)
+	_, _ = fmt.Fprintf(w, "tdata: dry run, %d golden files would change:\n", count)
+	for _, abs := range paths {
+		change := dryRunChanges.files[abs]
+		_, _ = fmt.Fprintf(w, "  %s: %s (%s)\n", abs, change, change.test)
+	}
+	return
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// withDryRun runs fn with dry run mode enabled and no changes noted,
+// restoring the previous state afterwards
+func withDryRun(fn func()) {
+	dryRunChanges.Lock()
+	files := dryRunChanges.files
+	dryRunChanges.files = make(map[string]dryRunChange)
+	dryRunChanges.Unlock()
+	*dryRunGoldens = true
+	defer func() {
+		*dryRunGoldens = false
+		dryRunChanges.Lock()
+		dryRunChanges.files = files
+		dryRunChanges.Unlock()
+	}()
+	fn()
+}
+
+func TestUpdate(t *testing.T) {
+
+	Convey("Update Scopes", t, func() {
+		for _, value := range []string{"", " ", "false", "0", ",", " , "} {
+			scope, err := parseUpdateScope(value)
+			So(err, ShouldBeNil)
+			So(scope, ShouldBeNil)
+		}
+		for _, value := range []string{"true", "1", "T"} {
+			scope, err := parseUpdateScope(value)
+			So(err, ShouldBeNil)
+			So(scope.all, ShouldBeTrue)
+		}
+		scope, err := parseUpdateScope("parser/*, /^lexer_.*\\.golden$/,TestScan")
+		So(err, ShouldBeNil)
+		So(scope.globs, ShouldEqual, []string{"parser/*", "TestScan"})
+		So(scope.patterns, ShouldHaveLength, 1)
+
+		for name, want := range map[[2]string]bool{
+			{"parser/expr.golden", "TestOther"}:        true,
+			{"parser/nested/expr.golden", "TestOther"}: true,
+			{"parser", "TestOther"}:                    false,
+			{"lexer_ident.golden", "TestOther"}:        true,
+			{"lexer/ident.golden", "TestOther"}:        false,
+			{"other.golden", "TestScan"}:               true,
+			{"other.golden", "TestScan/tokens"}:        true,
+			{"other.golden", "TestScanner"}:            false,
+		} {
+			So(scope.matches(name[0], name[1]), ShouldEqual, want)
+		}
+
+		for _, value := range []string{"[", "ok,/(/"} {
+			_, err = parseUpdateScope(value)
+			So(errors.Is(err, ErrUpdateScope), ShouldBeTrue)
+		}
+	})
+
+	Convey("Update Flag", t, func() {
+		value := &updateValue{}
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		fs.Var(value, "update", "")
+		So(fs.Parse([]string{"-update"}), ShouldBeNil)
+		So(value.String(), ShouldEqual, "true")
+		So(fs.Parse([]string{"-update=parser/*"}), ShouldBeNil)
+		So(value.String(), ShouldEqual, "parser/*")
+		So(fs.Parse([]string{"-update=["}), ShouldNotBeNil)
+		So(value.String(), ShouldEqual, "parser/*")
+		So((*updateValue)(nil).String(), ShouldEqual, "")
+
+		So(Updating(), ShouldBeFalse)
+		withUpdateScope("false", func() {
+			So(Updating(), ShouldBeFalse)
+		})
+		withUpdateScope("parser/*", func() {
+			So(Updating(), ShouldBeTrue)
+		})
+		t.Setenv(UpdateEnv, "lexer/*")
+		So(Updating(), ShouldBeTrue)
+		t.Setenv(UpdateEnv, "")
+		t.Setenv(DryRunEnv, "true")
+		So(DryRun(), ShouldBeTrue)
+		So(Updating(), ShouldBeTrue)
+		t.Setenv(DryRunEnv, "")
+		So(DryRun(), ShouldBeFalse)
+	})
+
+	Convey("Scoped Updates", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"parser/expr.golden": File("old\n"),
+			"lexer/ident.golden": File("old\n"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		withUpdateScope("parser/*", func() {
+			parser := newMockT("TestParser")
+			Golden(parser, tmpd, "parser/expr.golden", "new\n")
+			So(parser.failed(), ShouldEqual, "")
+			So(parser.logs, ShouldEqual, []string{"updated golden file: parser/expr.golden"})
+
+			lexer := newMockT("TestLexer")
+			Golden(lexer, tmpd, "lexer/ident.golden", "new\n")
+			So(lexer.failed(), ShouldStartWith, "golden file \"lexer/ident.golden\" mismatch:\n")
+		})
+		So(tmpd.F("parser/expr.golden"), ShouldEqual, "new\n")
+		So(tmpd.F("lexer/ident.golden"), ShouldEqual, "old\n")
+
+		withUpdateScope("/^TestLex/", func() {
+			lexer := newMockT("TestLexer/ident")
+			Golden(lexer, tmpd, "lexer/ident.golden", "new\n")
+			So(lexer.failed(), ShouldEqual, "")
+		})
+		So(tmpd.F("lexer/ident.golden"), ShouldEqual, "new\n")
+
+		t.Setenv(UpdateEnv, "[")
+		invalid := newMockT("TestInvalid")
+		Golden(invalid, tmpd, "parser/expr.golden", "other\n")
+		So(invalid.failed(), ShouldStartWith, "invalid TDATA_UPDATE: invalid update scope: \"[\": ")
+		So(tmpd.F("parser/expr.golden"), ShouldEqual, "new\n")
+		t.Setenv(UpdateEnv, "")
+	})
+
+	Convey("Dry Run", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"changed.golden": File("one\ntwo\nthree\n"),
+			"same.golden":    File("same\n"),
+			"scoped.golden":  File("old\n"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		var summary strings.Builder
+		withDryRun(func() {
+			So(Updating(), ShouldBeTrue)
+
+			changed := newMockT("TestChanged")
+			Golden(changed, tmpd, "changed.golden", "one\n2\n3\nfour\n")
+			So(changed.failed(), ShouldEqual, "")
+			So(changed.logs, ShouldEqual, []string{"golden file would change: changed.golden (+3 -2 lines)"})
+
+			created := newMockT("TestCreated")
+			Golden(created, tmpd, "nested/created.golden", "a\nb\n")
+			So(created.logs, ShouldEqual, []string{"golden file would change: nested/created.golden (new file, 2 lines)"})
+
+			same := newMockT("TestSame")
+			Golden(same, tmpd, "same.golden", "same\n")
+			So(same.logs, ShouldBeEmpty)
+
+			withUpdateScope("changed.golden", func() {
+				scoped := newMockT("TestScoped")
+				Golden(scoped, tmpd, "scoped.golden", "new\n")
+				So(scoped.failed(), ShouldStartWith, "golden file \"scoped.golden\" mismatch:\n")
+			})
+
+			So(writeDryRunSummary(&summary), ShouldEqual, 2)
+		})
+		So(summary.String(), ShouldEqual, ""+
+			"tdata: dry run, 2 golden files would change:\n"+
+			"  "+tmpd.Join("changed.golden")+": +3 -2 lines (TestChanged)\n"+
+			"  "+tmpd.Join("nested", "created.golden")+": new file, 2 lines (TestCreated)\n",
+		)
+		So(tmpd.F("changed.golden"), ShouldEqual, "one\ntwo\nthree\n")
+		So(tmpd.E("nested"), ShouldBeFalse)
+
+		summary.Reset()
+		withDryRun(func() {
+			So(writeDryRunSummary(&summary), ShouldEqual, 0)
+		})
+		So(summary.String(), ShouldEqual, "tdata: dry run, no golden files would change\n")
+	})
+
+	Convey("Conflicts", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		var wg sync.WaitGroup
+		tests := make([]*mockT, 8)
+		withUpdating(func() {
+			for idx := range tests {
+				tests[idx] = newMockT(fmt.Sprintf("TestShared/%d", idx))
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					Golden(tests[idx], tmpd, "shared.golden", "same\n")
+				}()
+			}
+			wg.Wait()
+		})
+		for _, mt := range tests {
+			So(mt.failed(), ShouldEqual, "")
+		}
+		So(tmpd.F("shared.golden"), ShouldEqual, "same\n")
+
+		var first string
+		withUpdating(func() {
+			for idx, content := range []string{"one\n", "two\n"} {
+				mt := newMockT(fmt.Sprintf("TestConflict/%d", idx))
+				GoldenBytes(mt, tmpd, "conflict.golden", []byte(content))
+				if idx == 0 {
+					first = mt.failed()
+				} else {
+					So(mt.fatal, ShouldBeTrue)
+					So(mt.failed(), ShouldEqual, "error updating golden file \"conflict.golden\": golden update conflict: different content was written by TestConflict/0")
+				}
+			}
+		})
+		So(first, ShouldEqual, "")
+		So(tmpd.F("conflict.golden"), ShouldEqual, "one\n")
+	})
+
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	clPath "github.com/go-corelibs/path"
+)
+
+const (
+	// UsageFlag is the name of the command-line flag which enables fixture
+	// usage tracking in Main, the value is the path of the JSON report to
+	// write or "-" to write the JSON report to stdout
+	UsageFlag = "tdata.usage"
+	// UsageEnv is the environment variable equivalent of the UsageFlag
+	UsageEnv = "TDATA_USAGE"
+)
+
+var usageReport = flag.String(UsageFlag, "", "track tdata fixture usage and write a JSON report of unused files to the given path")
+
+var usage = struct {
+	enabled atomic.Bool
+	sync.Mutex
+	roots map[string]struct{}
+	used  map[string]struct{}
+}{
+	roots: make(map[string]struct{}),
+	used:  make(map[string]struct{}),
+}
+
+// TrackUsage enables the recording of the fixture files accessed through
+// any TData, this is done by Main when the UsageFlag is given. All paths
+// returned by Join are counted as used, which covers the E, F, Open and L*
+// methods as well as files read directly from a joined path
+func TrackUsage() {
+	usage.enabled.Store(true)
+}
+
+// registerUsageRoot adds a TestData path to the roots reported by Usage
+func registerUsageRoot(root string) {
+	usage.Lock()
+	defer usage.Unlock()
+	usage.roots[root] = struct{}{}
+}
+
+func trackUsage(paths ...string) {
+	if !usage.enabled.Load() {
+		return
+	}
+	usage.Lock()
+	defer usage.Unlock()
+	for _, path := range paths {
+		usage.used[path] = struct{}{}
+	}
+}
+
+// UsageReport lists the used and unused fixture files of a TestData
+// directory, relative to the Root
+type UsageReport struct {
+	Root   string   `json:"root"`
+	Used   []string `json:"used"`
+	Unused []string `json:"unused"`
+}
+
+// Usage returns a UsageReport for each TestData directory constructed so
+// far, sorted by Root. Hidden files are not included
+func Usage() (reports []*UsageReport) {
+	usage.Lock()
+	defer usage.Unlock()
+
+	roots := make([]string, 0, len(usage.roots))
+	for root := range usage.roots {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+
+	for _, root := range roots {
+		report := &UsageReport{Root: root, Used: []string{}, Unused: []string{}}
+		files, _ := clPath.ListAllFiles(root, false)
+		sort.Strings(files)
+		for _, file := range files {
+			rel := strings.TrimPrefix(file, root+string(filepath.Separator))
+			if _, used := usage.used[file]; used {
+				report.Used = append(report.Used, rel)
+			} else {
+				report.Unused = append(report.Unused, rel)
+			}
+		}
+		reports = append(reports, report)
+	}
+	return
+}
+
+// usageReportPath returns the UsageFlag or UsageEnv value
+func usageReportPath() (path string) {
+	if path = *usageReport; path == "" {
+		path = os.Getenv(UsageEnv)
+	}
+	return
+}
+
+// writeUsage prints the unused fixture files of each report to w and writes
+// the JSON report to path, or to w when path is "-"
+func writeUsage(w io.Writer, path string) (err error) {
+	reports := Usage()
+	for _, report := range reports {
+		if len(report.Unused) == 0 {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "tdata: %d of %d fixture files in %s were never used:\n", len(report.Unused), len(report.Used)+len(report.Unused), report.Root)
+		for _, name := range report.Unused {
+			_, _ = fmt.Fprintf(w, "  %s\n", name)
+		}
+	}
+
+	var data []byte
+	if data, err = json.MarshalIndent(map[string][]*UsageReport{"reports": reports}, "", "  "); err != nil {
+		return
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		_, err = w.Write(data)
+		return
+	}
+	return os.WriteFile(path, data, 0644)
+}
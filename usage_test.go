@@ -0,0 +1,83 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// withUsage runs fn with usage tracking enabled and td registered as a
+// root, restoring the previous tracking state afterwards
+func withUsage(td TData, fn func()) {
+	usage.Lock()
+	roots, used := usage.roots, usage.used
+	usage.roots, usage.used = map[string]struct{}{td.Path(): {}}, make(map[string]struct{})
+	usage.Unlock()
+	enabled := usage.enabled.Load()
+	defer func() {
+		usage.Lock()
+		usage.roots, usage.used = roots, used
+		usage.Unlock()
+		usage.enabled.Store(enabled)
+	}()
+	TrackUsage()
+	fn()
+}
+
+func TestUsage(t *testing.T) {
+
+	Convey("Usage", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"read.txt":         File("read"),
+			"exists.txt":       File("exists"),
+			"opened.txt":       File("opened"),
+			"joined.txt":       File("joined"),
+			"listed/one.txt":   File("one"),
+			"listed/two.txt":   File("two"),
+			"unused.txt":       File("unused"),
+			"deep/unused.json": File("{}"),
+			".hidden":          File("hidden"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		// not tracked until enabled
+		usage.enabled.Store(false)
+		_ = tmpd.F("unused.txt")
+
+		withUsage(tmpd, func() {
+			_ = tmpd.F("read.txt")
+			_ = tmpd.E("exists.txt")
+			fh, err := tmpd.Open("opened.txt")
+			So(err, ShouldBeNil)
+			_ = fh.Close()
+			_, _ = os.ReadFile(tmpd.Join("joined.txt"))
+			_ = tmpd.LF("listed")
+
+			reports := Usage()
+			So(reports, ShouldHaveLength, 1)
+			So(reports[0], ShouldResemble, &UsageReport{
+				Root:   tmpd.Path(),
+				Used:   []string{"exists.txt", "joined.txt", "listed/one.txt", "listed/two.txt", "opened.txt", "read.txt"},
+				Unused: []string{"deep/unused.json", "unused.txt"},
+			})
+		})
+	})
+
+}
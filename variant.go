@@ -0,0 +1,107 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// Platform is what golden file variants are resolved for
+type Platform struct {
+	// GOOS is the operating system, such as "linux" or "windows"
+	GOOS string
+	// GOARCH is the architecture, such as "amd64" or "arm64"
+	GOARCH string
+	// GoVersion is the Go release, such as "go1.22", without the patch
+	// version
+	GoVersion string
+}
+
+// rxGoRelease matches the release within a runtime.Version
+var rxGoRelease = regexp.MustCompile(`go1\.\d+`)
+
+// CurrentPlatform returns the Platform the tests are running on
+func CurrentPlatform() (p Platform) {
+	return Platform{
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		GoVersion: rxGoRelease.FindString(runtime.Version()),
+	}
+}
+
+// goldenPlatform is the Platform golden file variants are resolved for
+var goldenPlatform = CurrentPlatform()
+
+// Variants returns the names of the variants of the golden file name, from
+// the most to the least specific, ending with the name itself. The variant
+// suffix is inserted before the extension of the name, or before the
+// extension preceding the CompressedExtension, and the precedence is:
+//
+//	name.linux_amd64.go1.22.golden
+//	name.linux_amd64.golden
+//	name.linux.go1.22.golden
+//	name.linux.golden
+//	name.go1.22.golden
+//	name.golden
+//
+// Variants for empty Platform fields are omitted
+func (p Platform) Variants(name string) (variants []string) {
+	var suffixes []string
+	if p.GOOS != "" {
+		if p.GOARCH != "" {
+			platform := p.GOOS + "_" + p.GOARCH
+			if p.GoVersion != "" {
+				suffixes = append(suffixes, platform+"."+p.GoVersion)
+			}
+			suffixes = append(suffixes, platform)
+		}
+		if p.GoVersion != "" {
+			suffixes = append(suffixes, p.GOOS+"."+p.GoVersion)
+		}
+		suffixes = append(suffixes, p.GOOS)
+	}
+	if p.GoVersion != "" {
+		suffixes = append(suffixes, p.GoVersion)
+	}
+
+	base, compressed := strings.CutSuffix(name, CompressedExtension)
+	ext := filepath.Ext(base)
+	base = strings.TrimSuffix(base, ext)
+	if compressed {
+		ext += CompressedExtension
+	}
+	for _, suffix := range suffixes {
+		variants = append(variants, base+"."+suffix+ext)
+	}
+	variants = append(variants, name)
+	return
+}
+
+// ResolveGolden returns the most specific variant of the golden file name,
+// for the Platform given, which exists within td, or the name itself when
+// none of the variants exist. See Platform.Variants for the precedence
+func ResolveGolden(td TData, name string, p Platform) (resolved string) {
+	for _, variant := range p.Variants(name) {
+		// not td.E, the variants which do not exist are not fixture usage
+		if _, err := os.Stat(filepath.Join(td.Path(), filepath.FromSlash(variant))); err == nil {
+			return variant
+		}
+	}
+	return name
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"runtime"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// withPlatform runs fn with golden file variants resolved for the Platform
+// given
+func withPlatform(p Platform, fn func()) {
+	defer func(previous Platform) { goldenPlatform = previous }(goldenPlatform)
+	goldenPlatform = p
+	fn()
+}
+
+func TestVariant(t *testing.T) {
+	linux := Platform{GOOS: "linux", GOARCH: "amd64", GoVersion: "go1.22"}
+
+	Convey("CurrentPlatform", t, func() {
+		p := CurrentPlatform()
+		So(p.GOOS, ShouldEqual, runtime.GOOS)
+		So(p.GOARCH, ShouldEqual, runtime.GOARCH)
+		So(p.GoVersion, ShouldStartWith, "go1.")
+		So(rxGoRelease.FindString("go1.22.4"), ShouldEqual, "go1.22")
+		So(rxGoRelease.FindString("devel go1.23-a1b2c3 Tue"), ShouldEqual, "go1.23")
+	})
+
+	Convey("Variants", t, func() {
+		So(linux.Variants("out.golden"), ShouldEqual, []string{
+			"out.linux_amd64.go1.22.golden",
+			"out.linux_amd64.golden",
+			"out.linux.go1.22.golden",
+			"out.linux.golden",
+			"out.go1.22.golden",
+			"out.golden",
+		})
+		So(linux.Variants("dir.v1/out.txt.golden.gz")[1], ShouldEqual, "dir.v1/out.txt.linux_amd64.golden.gz")
+		So(linux.Variants("dir.v1/output")[3], ShouldEqual, "dir.v1/output.linux")
+		So(Platform{GOOS: "windows"}.Variants("out.golden"), ShouldEqual, []string{"out.windows.golden", "out.golden"})
+		So(Platform{GOOS: "darwin", GoVersion: "go1.21"}.Variants("out.golden"), ShouldEqual, []string{
+			"out.darwin.go1.21.golden",
+			"out.darwin.golden",
+			"out.go1.21.golden",
+			"out.golden",
+		})
+		So(Platform{}.Variants("out.golden"), ShouldEqual, []string{"out.golden"})
+	})
+
+	Convey("ResolveGolden", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"out.golden":               File("generic\n"),
+			"out.go1.22.golden":        File("go1.22\n"),
+			"out.linux.golden":         File("linux\n"),
+			"nested/only.golden":       File("only\n"),
+			"err.linux_arm64.golden":   File("arm64\n"),
+			"err.windows_amd64.golden": File("windows\n"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		So(ResolveGolden(tmpd, "out.golden", linux), ShouldEqual, "out.linux.golden")
+		So(ResolveGolden(tmpd, "out.golden", Platform{GOOS: "darwin", GOARCH: "arm64", GoVersion: "go1.22"}), ShouldEqual, "out.go1.22.golden")
+		So(ResolveGolden(tmpd, "out.golden", Platform{GOOS: "darwin", GOARCH: "arm64", GoVersion: "go1.21"}), ShouldEqual, "out.golden")
+		So(ResolveGolden(tmpd, "nested/only.golden", linux), ShouldEqual, "nested/only.golden")
+		So(ResolveGolden(tmpd, "err.golden", linux), ShouldEqual, "err.golden")
+		So(ResolveGolden(tmpd, "err.golden", Platform{GOOS: "linux", GOARCH: "arm64"}), ShouldEqual, "err.linux_arm64.golden")
+	})
+
+	Convey("Golden Variants", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"out.golden":       File("generic\n"),
+			"out.linux.golden": File("linux\n"),
+			"new.golden":       File("generic\n"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		withPlatform(linux, func() {
+			match := newMockT("TestMatch")
+			Golden(match, tmpd, "out.golden", "linux\n")
+			So(match.failed(), ShouldEqual, "")
+
+			mismatch := newMockT("TestMismatch")
+			Golden(mismatch, tmpd, "out.golden", "generic\n")
+			So(mismatch.failed(), ShouldStartWith, "golden file \"out.linux.golden\" mismatch:\n")
+
+			withUpdating(func() {
+				updated := newMockT("TestUpdated")
+				Golden(updated, tmpd, "out.golden", "updated\n")
+				So(updated.logs, ShouldEqual, []string{"updated golden file: out.linux.golden"})
+
+				generic := newMockT("TestGeneric")
+				Golden(generic, tmpd, "new.golden", "updated\n")
+				So(generic.logs, ShouldEqual, []string{"updated golden file: new.golden"})
+			})
+		})
+		So(tmpd.F("out.linux.golden"), ShouldEqual, "updated\n")
+		So(tmpd.F("out.golden"), ShouldEqual, "generic\n")
+		So(tmpd.F("new.golden"), ShouldEqual, "updated\n")
+		So(tmpd.E("new.linux_amd64.go1.22.golden"), ShouldBeFalse)
+
+		withPlatform(Platform{GOOS: "windows"}, func() {
+			windows := newMockT("TestWindows")
+			Golden(windows, tmpd, "out.golden", "generic\n")
+			So(windows.failed(), ShouldEqual, "")
+		})
+	})
+
+	Convey("Golden Variant Scope", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+		So(Tree{
+			"out.golden":       File("generic\n"),
+			"out.linux.golden": File("linux\n"),
+			"other.golden":     File("generic\n"),
+		}.Apply(tmpd), ShouldBeNil)
+
+		withPlatform(linux, func() {
+			// the scope matches the resolved variant name
+			withUpdateScope("*.linux.golden", func() {
+				variant := newMockT("TestVariant")
+				Golden(variant, tmpd, "out.golden", "updated\n")
+				So(variant.logs, ShouldEqual, []string{"updated golden file: out.linux.golden"})
+
+				generic := newMockT("TestGeneric")
+				Golden(generic, tmpd, "other.golden", "updated\n")
+				So(generic.failed(), ShouldStartWith, "golden file \"other.golden\" mismatch:\n")
+			})
+			// and still the name given
+			withUpdateScope("out.golden", func() {
+				named := newMockT("TestNamed")
+				Golden(named, tmpd, "out.golden", "updated\n")
+				So(named.logs, ShouldEqual, []string{"updated golden file: out.linux.golden"})
+			})
+		})
+		So(tmpd.F("out.linux.golden"), ShouldEqual, "updated\n")
+		So(tmpd.F("out.golden"), ShouldEqual, "generic\n")
+		So(tmpd.F("other.golden"), ShouldEqual, "generic\n")
+	})
+
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"errors"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// pathLocks holds a *sync.Mutex for each path locked with lockPath
+var pathLocks sync.Map
+
+// lockPath locks the filename given for the current process, returning the
+// func which unlocks it. Paths are locked by their absolute form so that
+// different TData referring to the same file share the same lock
+func lockPath(filename string) (unlock func()) {
+	if abs, err := filepath.Abs(filename); err == nil {
+		filename = abs
+	}
+	value, _ := pathLocks.LoadOrStore(filename, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// writeFileAtomic writes data to the filename given, creating any missing
+// parent directories. The data is written to a temporary file in the same
+// directory which is then renamed over the filename, so that readers, other
+// processes included, see either the previous contents or the new contents
+// and never a partial write. An existing file keeps its mode while a new file
+// is created with perm less the umask, and symbolic links are written through
+// to the file they resolve to rather than being replaced
+func writeFileAtomic(filename string, data []byte, perm fs.FileMode) (err error) {
+	if resolved, ee := filepath.EvalSymlinks(filename); ee == nil {
+		filename = resolved
+	}
+	defer lockPath(filename)()
+
+	var existing fs.FileInfo
+	if info, ee := os.Stat(filename); ee == nil {
+		existing = info
+	}
+
+	dirname := filepath.Dir(filename)
+	if err = os.MkdirAll(dirname, DefaultDirMode); err != nil {
+		return
+	}
+	var tmp *os.File
+	if tmp, err = createTemp(dirname, "."+filepath.Base(filename)+".", ".tmp", perm); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return
+	} else if existing != nil {
+		if err = tmp.Chmod(existing.Mode().Perm()); err != nil {
+			return
+		}
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// createTemp is os.CreateTemp with the permission given, less the umask,
+// instead of always 0600
+func createTemp(dirname, prefix, suffix string, perm fs.FileMode) (tmp *os.File, err error) {
+	for range 10000 {
+		name := filepath.Join(dirname, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10)+suffix)
+		if tmp, err = os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm); !errors.Is(err, fs.ErrExist) {
+			return
+		}
+	}
+	err = &fs.PathError{Op: "createtemp", Path: filepath.Join(dirname, prefix+"*"+suffix), Err: fs.ErrExist}
+	return
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdata
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWrite(t *testing.T) {
+
+	Convey("lockPath", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		wd, err := os.Getwd()
+		So(err, ShouldBeNil)
+		rel, err := filepath.Rel(wd, tmpd.Join("file.txt"))
+		So(err, ShouldBeNil)
+
+		unlock := lockPath(tmpd.Join("file.txt"))
+		locked := make(chan struct{})
+		go func() {
+			lockPath(rel)()
+			close(locked)
+		}()
+		select {
+		case <-locked:
+			So("relative path was not locked", ShouldBeEmpty)
+		case <-time.After(20 * time.Millisecond):
+		}
+		unlock()
+		<-locked
+	})
+
+	Convey("writeFileAtomic", t, func() {
+		tmpd, err := NewTempData("", "tdata.*")
+		So(err, ShouldBeNil)
+		defer tmpd.Destroy()
+
+		// new files are created with the umask applied, as with os.WriteFile
+		So(os.WriteFile(tmpd.Join("umask.txt"), nil, 0666), ShouldBeNil)
+		umasked, err := os.Stat(tmpd.Join("umask.txt"))
+		So(err, ShouldBeNil)
+		So(os.Remove(tmpd.Join("umask.txt")), ShouldBeNil)
+
+		filename := tmpd.Join("nested", "file.txt")
+		So(writeFileAtomic(filename, []byte("one\n"), 0666), ShouldBeNil)
+		So(tmpd.F("nested/file.txt"), ShouldEqual, "one\n")
+		info, err := os.Stat(filename)
+		So(err, ShouldBeNil)
+		So(info.Mode().Perm(), ShouldEqual, umasked.Mode().Perm())
+
+		// existing files keep their mode
+		So(os.Chmod(filename, 0750), ShouldBeNil)
+		So(writeFileAtomic(filename, []byte("two\n"), 0644), ShouldBeNil)
+		So(tmpd.F("nested/file.txt"), ShouldEqual, "two\n")
+		info, err = os.Stat(filename)
+		So(err, ShouldBeNil)
+		So(info.Mode().Perm(), ShouldEqual, os.FileMode(0750))
+		So(os.Chmod(filename, 0644), ShouldBeNil)
+
+		// concurrent writers never interleave
+		var wg sync.WaitGroup
+		for idx := range 16 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = writeFileAtomic(filename, []byte(strings.Repeat(string(rune('a'+idx)), 1<<16)), 0644)
+			}()
+		}
+		wg.Wait()
+		data := tmpd.F("nested/file.txt")
+		So(data, ShouldHaveLength, 1<<16)
+		So(strings.Count(data, data[:1]), ShouldEqual, 1<<16)
+
+		entries, err := os.ReadDir(tmpd.Join("nested"))
+		So(err, ShouldBeNil)
+		So(entries, ShouldHaveLength, 1)
+
+		// symbolic links are written through
+		So(os.Symlink(filepath.Join("nested", "file.txt"), tmpd.Join("link.txt")), ShouldBeNil)
+		So(writeFileAtomic(tmpd.Join("link.txt"), []byte("linked\n"), 0644), ShouldBeNil)
+		target, err := os.Readlink(tmpd.Join("link.txt"))
+		So(err, ShouldBeNil)
+		So(target, ShouldEqual, filepath.Join("nested", "file.txt"))
+		So(tmpd.F("nested/file.txt"), ShouldEqual, "linked\n")
+
+		So(writeFileAtomic(tmpd.Join("nested", "file.txt", "below"), nil, 0644), ShouldNotBeNil)
+		So(writeFileAtomic(tmpd.Join("nested"), nil, 0644), ShouldNotBeNil)
+		entries, err = os.ReadDir(tmpd.Join("nested"))
+		So(err, ShouldBeNil)
+		So(entries, ShouldHaveLength, 1)
+	})
+
+}